@@ -25,7 +25,13 @@ from .estate import (
     list_estates,
     set_active_estate,
 )
-from .estate_execution import ExecutionIO, ExecutionOptions, run_apply, run_plan
+from .estate_execution import (
+    ExecutionIO,
+    ExecutionOptions,
+    GitHubAppCredentials,
+    run_apply,
+    run_plan,
+)
 from .listing import list_namespace_repositories
 from .persistence import PersistenceOptions, persist_estate
 from .platform_standards import PlatformStandardsConfig
@@ -53,10 +59,6 @@ ERROR_OWNER_LOOKUP_FAILED = (
     "`concordat estate init --github-owner <owner>` to record it."
 )
 ERROR_NO_ESTATES = "No estates configured. Run `concordat estate init` first."
-ERROR_MISSING_GITHUB_TOKEN = (
-    "GITHUB_TOKEN is required for concordat plan/apply; "  # noqa: S105
-    "pass --github-token or export the environment variable."
-)
 ERROR_AUTO_APPROVE_REQUIRED = "concordat apply requires --auto-approve to continue."
 ENV_SKIP_PLATFORM_PR = "CONCORDAT_SKIP_PLATFORM_PR"
 
@@ -351,24 +353,17 @@ def _resolve_estate_record(alias: str | None) -> EstateRecord:
     return _resolve_estate_or_active(alias, require_owner=False)
 
 
-def _resolve_github_token(explicit: str | None = None) -> str:
-    if not (token := explicit or os.getenv("GITHUB_TOKEN")):
-        raise ConcordatError(ERROR_MISSING_GITHUB_TOKEN)
-    return token
-
-
 @app.command()
 def plan(
     *tofu_args: str,
-    github_token: str | None = None,
     keep_workdir: bool = False,
 ) -> int:
     """Run `tofu plan` for the active estate."""
     record = _require_active_estate()
-    token = _resolve_github_token(github_token)
+    github_app = GitHubAppCredentials.from_environment(os.environ)
     options = ExecutionOptions(
         github_owner=record.github_owner or "",
-        github_token=token,
+        github_app=github_app,
         extra_args=tofu_args,
         keep_workdir=keep_workdir,
     )
@@ -380,7 +375,6 @@ def plan(
 @app.command()
 def apply(
     *tofu_args: str,
-    github_token: str | None = None,
     auto_approve: bool = False,
     keep_workdir: bool = False,
 ) -> int:
@@ -388,11 +382,11 @@ def apply(
     if not auto_approve:
         raise ConcordatError(ERROR_AUTO_APPROVE_REQUIRED)
     record = _require_active_estate()
-    token = _resolve_github_token(github_token)
+    github_app = GitHubAppCredentials.from_environment(os.environ)
     args = _ensure_auto_approve_flag(tuple(tofu_args))
     options = ExecutionOptions(
         github_owner=record.github_owner or "",
-        github_token=token,
+        github_app=github_app,
         extra_args=args,
         keep_workdir=keep_workdir,
     )
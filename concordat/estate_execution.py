@@ -129,12 +129,54 @@ def _resolve_backend_environment(env: typ.Mapping[str, str]) -> dict[str, str]:
         raise EstateExecutionError(str(error)) from error
 
 
+# Environment variables the github provider's app_auth block reads.
+GITHUB_APP_ENV = (
+    "GITHUB_APP_ID",
+    "GITHUB_APP_INSTALLATION_ID",
+    "GITHUB_APP_PEM_FILE",
+)
+
+ERROR_GITHUB_APP_ENV_MISSING = (
+    "concordat plan/apply authenticate OpenTofu as a GitHub App; export {missing}."
+)
+
+
+@dataclasses.dataclass(frozen=True)
+class GitHubAppCredentials:
+    """GitHub App identity handed to tofu for the provider's app_auth block."""
+
+    app_id: str
+    installation_id: str
+    pem_file: str
+
+    @classmethod
+    def from_environment(cls, env: cabc.Mapping[str, str]) -> GitHubAppCredentials:
+        """Read the app credentials from env, naming any that are missing."""
+        values = {name: env.get(name, "").strip() for name in GITHUB_APP_ENV}
+        if missing := [name for name, value in values.items() if not value]:
+            raise ConcordatError(
+                ERROR_GITHUB_APP_ENV_MISSING.format(missing=", ".join(missing))
+            )
+        app_id, installation_id, pem_file = values.values()
+        return cls(app_id=app_id, installation_id=installation_id, pem_file=pem_file)
+
+    def as_environment(self) -> dict[str, str]:
+        """Return the environment variables the provider reads."""
+        return dict(
+            zip(
+                GITHUB_APP_ENV,
+                (self.app_id, self.installation_id, self.pem_file),
+                strict=True,
+            )
+        )
+
+
 @dataclasses.dataclass(frozen=True)
 class ExecutionOptions:
     """User-configurable knobs for running tofu against an estate."""
 
     github_owner: str
-    github_token: str
+    github_app: GitHubAppCredentials
     extra_args: cabc.Sequence[str] = dataclasses.field(default_factory=tuple)
     keep_workdir: bool = False
     cache_directory: Path | None = None
@@ -288,7 +330,9 @@ def _setup_tofu_workspace(
         execution.env,
         execution.io,
     )
-    env["GITHUB_TOKEN"] = execution.options.github_token
+    # The provider authenticates through app_auth only; never hand tofu a PAT.
+    env.pop("GITHUB_TOKEN", None)
+    env.update(execution.options.github_app.as_environment())
     tofu = _initialize_tofu(workspace.tofu_dir, env)
 
     return backend_args, tofu
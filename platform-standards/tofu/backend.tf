@@ -8,5 +8,9 @@ terraform {
     }
   }
 
-  backend "s3" {}
+  # Everything estate-specific comes from a tfbackend file; encryption is
+  # fixed here so no estate can opt out of it.
+  backend "s3" {
+    encrypt = true
+  }
 }
@@ -0,0 +1,21 @@
+mock_provider "github" {
+  alias = "mock"
+}
+
+run "actions_permissions_baseline" {
+  command = plan
+
+  providers = {
+    github = github.mock
+  }
+
+  module {
+    source = "./.."
+  }
+
+  variables {
+    repository       = "standards-repo"
+    patterns_allowed = ["leynos/concordat/*"]
+  }
+
+}
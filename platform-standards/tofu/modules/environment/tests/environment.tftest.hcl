@@ -0,0 +1,22 @@
+mock_provider "github" {
+  alias = "mock"
+}
+
+run "environment_baseline" {
+  command = plan
+
+  providers = {
+    github = github.mock
+  }
+
+  module {
+    source = "./.."
+  }
+
+  variables {
+    repository        = "standards-repo"
+    environment       = "production"
+    reviewer_team_ids = [4242]
+  }
+
+}
@@ -28,6 +28,14 @@ run "repository_defaults" {
     )
     error_message = "merge commits and rebase merges must be disabled by default"
   }
+
+  assert {
+    condition = (
+      github_repository.this.squash_merge_commit_title == "PR_TITLE" &&
+      github_repository.this.squash_merge_commit_message == "COMMIT_MESSAGES"
+    )
+    error_message = "squash commits should default to the PR title and commit messages"
+  }
 }
 
 run "repository_apply_smoke" {
@@ -0,0 +1,41 @@
+mock_provider "github" {
+  alias = "mock"
+}
+
+run "ruleset_baseline" {
+  command = plan
+
+  providers = {
+    github = github.mock
+  }
+
+  module {
+    source = "./.."
+  }
+
+  variables {
+    repository = "standards-repo"
+    status_checks = {
+      checks = [
+        { context = "concordat/auditor", integration_id = 15368 },
+      ]
+    }
+  }
+
+  assert {
+    condition     = github_repository_ruleset.this.enforcement == "active"
+    error_message = "ruleset must be actively enforced"
+  }
+
+  assert {
+    condition     = github_repository_ruleset.this.conditions[0].ref_name[0].include == tolist(["~DEFAULT_BRANCH"])
+    error_message = "ruleset should target the default branch"
+  }
+
+  assert {
+    condition = (
+      [for check in github_repository_ruleset.this.rules[0].required_status_checks[0].required_check : check.context] == ["concordat/auditor"]
+    )
+    error_message = "ruleset should require the concordat/auditor status check"
+  }
+}
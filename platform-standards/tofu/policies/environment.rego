@@ -0,0 +1,13 @@
+package platform_standards.environment
+
+import rego.v1
+
+minimum_wait_minutes := 5
+
+deny contains msg if {
+  change := input.resource_changes[_]
+  change.type == "github_repository_environment"
+  wait_timer := object.get(change.change.after, "wait_timer", 0)
+  wait_timer < minimum_wait_minutes
+  msg := sprintf("environment %s waits %v minutes; at least %v are required", [change.address, wait_timer, minimum_wait_minutes])
+}
@@ -0,0 +1,45 @@
+package platform_standards.environment
+
+import rego.v1
+
+violations_for(cfg) := {msg |
+  data.platform_standards.environment.deny[msg] with input as cfg
+}
+
+test_environment_wait_timer_allowed if {
+  cfg := {
+    "resource_changes": [
+      {
+        "address": "module.environment.github_repository_environment.this",
+        "type": "github_repository_environment",
+        "change": {
+          "after": {
+            "wait_timer": 10
+          }
+        }
+      }
+    ]
+  }
+
+  count(violations_for(cfg)) == 0
+}
+
+test_environment_rejects_short_wait if {
+  cfg := {
+    "resource_changes": [
+      {
+        "address": "env.production",
+        "type": "github_repository_environment",
+        "change": {
+          "after": {
+            "wait_timer": 1
+          }
+        }
+      }
+    ]
+  }
+
+  violations := violations_for(cfg)
+  expected := "environment env.production waits 1 minutes; at least 5 are required"
+  violations[expected]
+}
@@ -26,7 +26,7 @@ deny contains msg if {
   msg := sprintf("team membership %s uses unexpected role %s", [change.address, role])
 }
 
-allowed_permissions := {"pull", "triage", "push", "maintain", "admin"}
+allowed_permissions := {"pull", "triage", "push", "maintain"}
 
 allowed_permission(p) if {
   allowed_permissions[p]
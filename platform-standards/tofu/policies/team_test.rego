@@ -52,3 +52,23 @@ test_team_rejects_unknown_permission if {
   expected := "team repo binding team.repo uses disallowed permission owner"
   violations[expected]
 }
+
+test_team_rejects_admin_permission if {
+  cfg := {
+    "resource_changes": [
+      {
+        "address": "team.repo",
+        "type": "github_team_repository",
+        "change": {
+          "after": {
+            "permission": "admin"
+          }
+        }
+      }
+    ]
+  }
+
+  violations := violations_for(cfg)
+  expected := "team repo binding team.repo uses disallowed permission admin"
+  violations[expected]
+}
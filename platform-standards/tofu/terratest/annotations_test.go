@@ -0,0 +1,118 @@
+package terratest
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+)
+
+// annotationOutput receives workflow commands; GitHub Actions reads them from
+// stdout.
+var annotationOutput io.Writer = os.Stdout
+
+// annotate emits a GitHub Actions error annotation pointing at file and line
+// so guardrail failures show inline on the pull request. It does nothing
+// outside GitHub Actions.
+func annotate(t *testing.T, file string, line int, message string) {
+	t.Helper()
+
+	if os.Getenv("GITHUB_ACTIONS") != "true" {
+		return
+	}
+	fmt.Fprintf(annotationOutput, "::error file=%s,line=%d::%s\n",
+		escapeAnnotationProperty(annotationPath(file)), line, escapeAnnotationData(message))
+}
+
+// fatalAt annotates rng and fails the test with the same message.
+func fatalAt(t *testing.T, rng hcl.Range, format string, args ...interface{}) {
+	t.Helper()
+
+	message := fmt.Sprintf(format, args...)
+	annotate(t, rng.Filename, rng.Start.Line, message)
+	t.Fatal(message)
+}
+
+// annotationPath rewrites file relative to GITHUB_WORKSPACE, since
+// annotations resolve paths from the repository root rather than the
+// terratest directory.
+func annotationPath(file string) string {
+	workspace := os.Getenv("GITHUB_WORKSPACE")
+	if workspace == "" {
+		return filepath.ToSlash(file)
+	}
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(workspace, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// escapeAnnotationData applies the workflow command escaping for messages.
+func escapeAnnotationData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeAnnotationProperty additionally escapes the property separators.
+func escapeAnnotationProperty(s string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeAnnotationData(s))
+}
+
+// captureStdout swaps os.Stdout for a pipe while fn runs and returns what was
+// written.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+	original, originalOutput := os.Stdout, annotationOutput
+	os.Stdout, annotationOutput = w, w
+	defer func() { os.Stdout, annotationOutput = original, originalOutput }()
+
+	fn()
+	w.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, r); err != nil {
+		t.Fatalf("read captured stdout: %v", err)
+	}
+	return buf.String()
+}
+
+// TestAnnotateFormatsWorkflowCommand checks the ::error command format,
+// workspace-relative paths, and escaping.
+func TestAnnotateFormatsWorkflowCommand(t *testing.T) {
+	workspace := t.TempDir()
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_WORKSPACE", workspace)
+
+	file := filepath.Join(workspace, "platform-standards", "tofu", "backend.tf")
+	got := captureStdout(t, func() {
+		annotate(t, file, 7, "expected \"~> 6.3\", got 50%\nsee docs")
+	})
+
+	want := "::error file=platform-standards/tofu/backend.tf,line=7::expected \"~> 6.3\", got 50%25%0Asee docs\n"
+	if got != want {
+		t.Fatalf("unexpected annotation:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+// TestAnnotateIsSilentOutsideActions ensures local runs stay free of
+// workflow commands.
+func TestAnnotateIsSilentOutsideActions(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+
+	if got := captureStdout(t, func() { annotate(t, "backend.tf", 1, "message") }); got != "" {
+		t.Fatalf("expected no output outside GitHub Actions, got %q", got)
+	}
+}
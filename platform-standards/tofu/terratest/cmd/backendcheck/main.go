@@ -0,0 +1,77 @@
+// Command backendcheck validates Scaleway tfbackend files against the rules
+// the terratest suite enforces, so operators can check a hand-edited file
+// before running tofu init.
+//
+// Usage:
+//
+//	go run ./cmd/backendcheck path/to/estate.tfbackend [more.tfbackend ...]
+//
+// Each file is reported as PASS or FAIL, with one line per broken rule. The
+// command exits 1 when any file fails and 2 on a usage error.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendcheck"
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendconfig"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("backendcheck", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() == 0 {
+		fmt.Fprintln(stderr, "backendcheck: at least one tfbackend path is required")
+		return 2
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		errs := check(path)
+		if len(errs) == 0 {
+			fmt.Fprintf(stdout, "PASS %s\n", path)
+			continue
+		}
+		code = 1
+		fmt.Fprintf(stdout, "FAIL %s\n", path)
+		for _, err := range errs {
+			fmt.Fprintf(stdout, "  - %v\n", err)
+		}
+	}
+	return code
+}
+
+// check loads path and runs the Scaleway rules against it. A file that
+// cannot be decoded, or that uses interpolation tofu would not expand, fails
+// with those errors alone.
+func check(path string) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("read backend config %s: %w", path, err)}
+	}
+	file, diags := hclsyntax.ParseConfig(data, path, hcl.InitialPos)
+	if diags.HasErrors() {
+		return []error{fmt.Errorf("parse backend config %s: %s", path, diags.Error())}
+	}
+	if errs := backendcheck.InterpolationViolations(file.Body.(*hclsyntax.Body)); len(errs) > 0 {
+		return errs
+	}
+
+	cfg, err := backendconfig.Decode(path, data)
+	if err != nil {
+		return []error{err}
+	}
+	return backendcheck.Scaleway(cfg)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunPassesCommittedScalewayBackend checks the committed template passes.
+func TestRunPassesCommittedScalewayBackend(t *testing.T) {
+	path := filepath.Join("..", "..", "..", "backend", "scaleway.tfbackend")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+	if got, want := stdout.String(), "PASS "+path+"\n"; got != want {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+}
+
+// TestRunReportsEachViolation checks a failing file exits non-zero and names
+// the rule it breaks.
+func TestRunReportsEachViolation(t *testing.T) {
+	path := filepath.Join("..", "..", "testdata", "backend", "scaleway_region_mismatch.tfbackend")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{path}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1, got %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+
+	got := stdout.String()
+	for _, want := range []string{
+		"FAIL " + path,
+		`unexpected region "nl-ams"`,
+		`expected "https://s3.nl-ams.scw.cloud"`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+}
+
+// TestRunFlagsInterpolation checks ${...} values are reported by attribute
+// instead of surfacing as a decode error.
+func TestRunFlagsInterpolation(t *testing.T) {
+	path := filepath.Join("..", "..", "testdata", "backend", "interpolated.tfbackend")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{path}, &stdout, &stderr); code != 1 {
+		t.Fatalf("expected exit 1, got %d:\n%s%s", code, stdout.String(), stderr.String())
+	}
+
+	got := stdout.String()
+	for _, want := range []string{"access_key uses interpolation syntax", "endpoints uses interpolation syntax"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("report missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "decode backend config") {
+		t.Errorf("expected no decode error once interpolation is reported:\n%s", got)
+	}
+}
+
+// TestRunRejectsBadInput covers a missing path argument and an unreadable
+// file.
+func TestRunRejectsBadInput(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run(nil, &stdout, &stderr); code != 2 {
+		t.Errorf("expected exit 2 without a path, got %d", code)
+	}
+
+	stdout.Reset()
+	missing := filepath.Join(t.TempDir(), "missing.tfbackend")
+	if code := run([]string{missing}, &stdout, &stderr); code != 1 {
+		t.Errorf("expected exit 1 for a missing file, got %d", code)
+	}
+	if !strings.Contains(stdout.String(), "read backend config") {
+		t.Errorf("expected the read error to be reported, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,79 @@
+// Command backendgen renders a tfbackend file for one estate stack so
+// operators do not hand-edit state keys.
+//
+// Usage:
+//
+//	go run ./cmd/backendgen --provider scaleway --estate foo --stack main [--out path]
+//
+// The file is written to stdout unless --out is given. Credentials are never
+// rendered; export them as environment variables before running tofu init.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendcheck"
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendconfig"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("backendgen", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	provider := flags.String("provider", "", "backend provider to render (scaleway)")
+	estate := flags.String("estate", "", "estate name used in the state key")
+	stack := flags.String("stack", "", "stack name used in the state key")
+	outPath := flags.String("out", "", "write the tfbackend here instead of stdout")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	rendered, err := render(*provider, *estate, *stack)
+	if err != nil {
+		fmt.Fprintf(stderr, "backendgen: %v\n", err)
+		return 1
+	}
+
+	if *outPath == "" {
+		if _, err := stdout.Write(rendered); err != nil {
+			fmt.Fprintf(stderr, "backendgen: write stdout: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if err := os.WriteFile(*outPath, rendered, 0o644); err != nil {
+		fmt.Fprintf(stderr, "backendgen: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// render builds the tfbackend for provider. Only Scaleway is supported; AWS
+// estates keep their hand-maintained file.
+func render(provider, estate, stack string) ([]byte, error) {
+	if estate == "" || stack == "" {
+		return nil, errors.New("--estate and --stack are required")
+	}
+
+	switch provider {
+	case "scaleway":
+		cfg, err := backendconfig.Scaleway(backendcheck.ScalewayBucket, backendcheck.ScalewayRegion, estate, stack)
+		if err != nil {
+			return nil, err
+		}
+		header := fmt.Sprintf("Scaleway Object Storage backend for the %s estate, %s stack.\n", estate, stack) +
+			"Do not add credentials here; export SCW_ACCESS_KEY/SCW_SECRET_KEY instead."
+		return backendconfig.Render(cfg, header)
+	case "":
+		return nil, errors.New("--provider is required")
+	default:
+		return nil, fmt.Errorf("unsupported provider %q; only scaleway is supported", provider)
+	}
+}
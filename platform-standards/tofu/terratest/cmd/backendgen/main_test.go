@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRunRendersScalewayBackend checks the rendered key and skip flags.
+func TestRunRendersScalewayBackend(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--provider", "scaleway", "--estate", "foo", "--stack", "main"}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+
+	got := stdout.String()
+	for _, want := range []string{
+		`"estates/foo/main/terraform.tfstate"`,
+		`use_path_style              = true`,
+		`skip_region_validation      = true`,
+		`skip_requesting_account_id  = true`,
+		`skip_credentials_validation = true`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("rendered backend missing %q:\n%s", want, got)
+		}
+	}
+	for _, banned := range []string{"access_key", "secret_key", "session_token", "profile", "null"} {
+		if strings.Contains(got, banned) {
+			t.Errorf("rendered backend contains %q:\n%s", banned, got)
+		}
+	}
+}
+
+// TestRunWritesOutPath checks --out writes the file and leaves stdout empty.
+func TestRunWritesOutPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foo.tfbackend")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"--provider", "scaleway", "--estate", "foo", "--stack", "main", "--out", path}, &stdout, &stderr); code != 0 {
+		t.Fatalf("run exited %d: %s", code, stderr.String())
+	}
+	if stdout.Len() != 0 {
+		t.Errorf("expected empty stdout, got %q", stdout.String())
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("expected %s to exist: %v", path, err)
+	}
+}
+
+// TestRunRejectsBadInput covers unsupported providers and malformed names.
+func TestRunRejectsBadInput(t *testing.T) {
+	cases := map[string][]string{
+		"unsupported provider": {"--provider", "gcs", "--estate", "foo", "--stack", "main"},
+		"missing provider":     {"--estate", "foo", "--stack", "main"},
+		"missing stack":        {"--provider", "scaleway", "--estate", "foo"},
+		"upper-case estate":    {"--provider", "scaleway", "--estate", "Foo", "--stack", "main"},
+		"nested stack":         {"--provider", "scaleway", "--estate", "foo", "--stack", "a/b"},
+	}
+	for name, args := range cases {
+		t.Run(name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(args, &stdout, &stderr); code == 0 {
+				t.Fatalf("expected failure, got output:\n%s", stdout.String())
+			}
+			if stdout.Len() != 0 {
+				t.Errorf("expected no output on failure, got %q", stdout.String())
+			}
+		})
+	}
+}
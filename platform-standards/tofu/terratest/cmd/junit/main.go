@@ -0,0 +1,349 @@
+// Command junit converts `go test -json` output from the Terratest suite into
+// a JUnit XML report for CI dashboards.
+//
+// Usage:
+//
+//	go test -json ./... | CONCORDAT_JUNIT_OUT=report.xml go run ./cmd/junit
+//
+// Test output is echoed to stdout as plain text so logs read as they would
+// without -json. The command exits non-zero when any test or package fails,
+// so it can sit at the end of a pipeline. Without CONCORDAT_JUNIT_OUT it only
+// echoes and reports the exit status.
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// testEvent mirrors the records emitted by `go test -json`.
+type testEvent struct {
+	Time    time.Time
+	Action  string
+	Package string
+	Test    string
+	Elapsed float64
+	Output  string
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// packageResult accumulates the events for one Go package.
+type packageResult struct {
+	name    string
+	started time.Time
+	elapsed float64
+	failed  bool
+	output  strings.Builder
+	tests   map[string]*testResult
+	order   []string
+}
+
+type testResult struct {
+	action  string
+	elapsed float64
+	output  strings.Builder
+}
+
+// report is the parsed form of a `go test -json` stream.
+type report struct {
+	packages map[string]*packageResult
+	order    []string
+}
+
+func main() {
+	modulesDir := flag.String("modules", "../modules", "directory holding the OpenTofu modules used to classify tests")
+	flag.Parse()
+
+	os.Exit(run(os.Stdin, os.Stdout, os.Getenv("CONCORDAT_JUNIT_OUT"), *modulesDir))
+}
+
+func run(in io.Reader, echo io.Writer, outPath, modulesDir string) int {
+	rep, err := parseEvents(in, echo)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read go test events: %v\n", err)
+		return 1
+	}
+
+	if outPath != "" {
+		modules, err := moduleNames(modulesDir)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "list modules: %v\n", err)
+			return 1
+		}
+		if err := writeReport(outPath, buildJUnit(rep, modules)); err != nil {
+			fmt.Fprintf(os.Stderr, "write JUnit report: %v\n", err)
+			return 1
+		}
+	}
+
+	if rep.failed() {
+		return 1
+	}
+	return 0
+}
+
+// parseEvents reads `go test -json` records, echoing their output as it goes.
+// Lines that are not JSON and build events that carry no package are only
+// echoed; the failing package's own fail event marks the failure.
+func parseEvents(in io.Reader, echo io.Writer) (*report, error) {
+	rep := &report{packages: map[string]*packageResult{}}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var event testEvent
+		if err := json.Unmarshal(line, &event); err != nil || event.Action == "" {
+			fmt.Fprintln(echo, string(line))
+			continue
+		}
+		if event.Output != "" {
+			io.WriteString(echo, event.Output)
+		}
+		if event.Package != "" {
+			rep.record(event)
+		}
+	}
+	return rep, scanner.Err()
+}
+
+func (r *report) pkg(name string) *packageResult {
+	pkg, ok := r.packages[name]
+	if !ok {
+		pkg = &packageResult{name: name, tests: map[string]*testResult{}}
+		r.packages[name] = pkg
+		r.order = append(r.order, name)
+	}
+	return pkg
+}
+
+func (r *report) record(event testEvent) {
+	pkg := r.pkg(event.Package)
+	if pkg.started.IsZero() && !event.Time.IsZero() {
+		pkg.started = event.Time
+	}
+
+	if event.Test == "" {
+		switch event.Action {
+		case "output":
+			pkg.output.WriteString(event.Output)
+		case "pass", "skip":
+			pkg.elapsed = event.Elapsed
+		case "fail":
+			pkg.elapsed = event.Elapsed
+			pkg.failed = true
+		}
+		return
+	}
+
+	test, ok := pkg.tests[event.Test]
+	if !ok {
+		test = &testResult{}
+		pkg.tests[event.Test] = test
+		pkg.order = append(pkg.order, event.Test)
+	}
+	switch event.Action {
+	case "output":
+		test.output.WriteString(event.Output)
+	case "pass", "fail", "skip":
+		test.action = event.Action
+		test.elapsed = event.Elapsed
+	}
+}
+
+// failed reports whether any package or test failed. Tests that never
+// finished, for example after a panic or timeout, count as failures.
+func (r *report) failed() bool {
+	for _, pkg := range r.packages {
+		if pkg.failed {
+			return true
+		}
+		for _, test := range pkg.tests {
+			if test.action == "fail" || test.action == "" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// buildJUnit turns a report into one testsuite per package. Each classname is
+// the package joined with the OpenTofu module the test covers, so dashboards
+// can group results per module.
+func buildJUnit(rep *report, modules []string) junitTestSuites {
+	var suites junitTestSuites
+	var total float64
+
+	for _, name := range rep.order {
+		pkg := rep.packages[name]
+		suite := junitTestSuite{
+			Name: pkg.name,
+			Time: seconds(pkg.elapsed),
+		}
+		if !pkg.started.IsZero() {
+			suite.Timestamp = pkg.started.UTC().Format(time.RFC3339)
+		}
+
+		for _, testName := range pkg.order {
+			test := pkg.tests[testName]
+			testCase := junitTestCase{
+				Name:      testName,
+				Classname: pkg.name + "." + moduleForTest(testName, modules),
+				Time:      seconds(test.elapsed),
+			}
+			switch test.action {
+			case "fail", "":
+				message := "test failed"
+				if test.action == "" {
+					message = "test did not finish"
+				}
+				testCase.Failure = &junitMessage{Message: message, Body: test.output.String()}
+				suite.Failures++
+			case "skip":
+				testCase.Skipped = &junitMessage{Message: skipMessage(test.output.String()), Body: test.output.String()}
+				suite.Skipped++
+			default:
+				testCase.SystemOut = test.output.String()
+			}
+			suite.Cases = append(suite.Cases, testCase)
+		}
+
+		// A package that fails without a failing test, such as a build error
+		// or a TestMain exit, still needs to show up on the dashboard.
+		if pkg.failed && suite.Failures == 0 {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      "package",
+				Classname: pkg.name + ".suite",
+				Time:      seconds(pkg.elapsed),
+				Failure:   &junitMessage{Message: "package failed", Body: pkg.output.String()},
+			})
+			suite.Failures++
+		}
+
+		suite.Tests = len(suite.Cases)
+		suites.Tests += suite.Tests
+		suites.Failures += suite.Failures
+		suites.Skipped += suite.Skipped
+		total += pkg.elapsed
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = seconds(total)
+	return suites
+}
+
+// moduleForTest maps a test name to the module directory it exercises by
+// matching the CamelCase module name after the Test prefix, so
+// TestActionsPermissionsRejectsAllActions belongs to actions_permissions.
+// Tests that match no module are grouped under "suite".
+func moduleForTest(testName string, modules []string) string {
+	name := strings.TrimPrefix(strings.SplitN(testName, "/", 2)[0], "Test")
+
+	best := ""
+	for _, module := range modules {
+		prefix := camelCase(module)
+		if !strings.HasPrefix(name, prefix) || len(prefix) <= len(camelCase(best)) {
+			continue
+		}
+		// Require a word boundary so a "team" module does not claim
+		// TestTeammate.
+		if rest := name[len(prefix):]; rest != "" && !unicode.IsUpper(rune(rest[0])) {
+			continue
+		}
+		best = module
+	}
+	if best == "" {
+		return "suite"
+	}
+	return best
+}
+
+func camelCase(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' }) {
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// skipMessage returns the last non-empty line of a skipped test's output,
+// which holds the reason passed to t.Skip.
+func skipMessage(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if line != "" && !strings.HasPrefix(line, "--- SKIP") {
+			return line
+		}
+	}
+	return "skipped"
+}
+
+func seconds(elapsed float64) string {
+	return fmt.Sprintf("%.3f", elapsed)
+}
+
+// moduleNames lists the module directories used to classify tests.
+func moduleNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func writeReport(path string, suites junitTestSuites) error {
+	data, err := xml.MarshalIndent(suites, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xml.Header), data...)
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
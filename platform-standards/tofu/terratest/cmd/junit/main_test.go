@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const syntheticEvents = `{"Time":"2026-01-02T03:04:05Z","Action":"start","Package":"example/terratest"}
+{"Action":"run","Package":"example/terratest","Test":"TestRepositoryModuleDefaults"}
+{"Action":"output","Package":"example/terratest","Test":"TestRepositoryModuleDefaults","Output":"=== RUN   TestRepositoryModuleDefaults\n"}
+{"Action":"pass","Package":"example/terratest","Test":"TestRepositoryModuleDefaults","Elapsed":1.5}
+{"Action":"run","Package":"example/terratest","Test":"TestActionsPermissionsRejectsAllActions"}
+{"Action":"output","Package":"example/terratest","Test":"TestActionsPermissionsRejectsAllActions","Output":"    modules_test.go:10: expected plan to fail\n"}
+{"Action":"fail","Package":"example/terratest","Test":"TestActionsPermissionsRejectsAllActions","Elapsed":0.25}
+{"Action":"run","Package":"example/terratest","Test":"TestParseTofuMatrix"}
+{"Action":"output","Package":"example/terratest","Test":"TestParseTofuMatrix","Output":"    versions_test.go:5: tofu not installed\n"}
+{"Action":"skip","Package":"example/terratest","Test":"TestParseTofuMatrix","Elapsed":0}
+{"Action":"fail","Package":"example/terratest","Elapsed":1.9}
+`
+
+// writeModules creates empty module directories for classification.
+func writeModules(t *testing.T, names ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.Mkdir(filepath.Join(dir, name), 0o755); err != nil {
+			t.Fatalf("create module %s: %v", name, err)
+		}
+	}
+	return dir
+}
+
+// TestRunWritesJUnitReport converts synthetic events and checks the report
+// carries the JUnit structure, timings, and module classnames.
+func TestRunWritesJUnitReport(t *testing.T) {
+	modules := writeModules(t, "actions_permissions", "repository", "team")
+	out := filepath.Join(t.TempDir(), "report.xml")
+	var echo bytes.Buffer
+
+	if code := run(strings.NewReader(syntheticEvents), &echo, out, modules); code != 1 {
+		t.Fatalf("expected exit code 1 for a failing suite, got %d", code)
+	}
+	if !strings.Contains(echo.String(), "expected plan to fail") {
+		t.Fatalf("expected test output to be echoed, got %q", echo.String())
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte(xml.Header)) {
+		t.Fatalf("expected an XML declaration, got %q", data[:min(len(data), 40)])
+	}
+
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("parse report: %v", err)
+	}
+	if suites.XMLName.Local != "testsuites" || len(suites.Suites) != 1 {
+		t.Fatalf("expected one testsuite under testsuites, got %#v", suites)
+	}
+	if suites.Tests != 3 || suites.Failures != 1 || suites.Skipped != 1 {
+		t.Fatalf("expected 3 tests, 1 failure, 1 skip, got %d/%d/%d", suites.Tests, suites.Failures, suites.Skipped)
+	}
+
+	suite := suites.Suites[0]
+	if suite.Name != "example/terratest" || suite.Time != "1.900" || suite.Timestamp != "2026-01-02T03:04:05Z" {
+		t.Fatalf("unexpected testsuite attributes: %#v", suite)
+	}
+
+	want := []struct {
+		name, classname, time string
+		failed, skipped       bool
+	}{
+		{"TestRepositoryModuleDefaults", "example/terratest.repository", "1.500", false, false},
+		{"TestActionsPermissionsRejectsAllActions", "example/terratest.actions_permissions", "0.250", true, false},
+		{"TestParseTofuMatrix", "example/terratest.suite", "0.000", false, true},
+	}
+	if len(suite.Cases) != len(want) {
+		t.Fatalf("expected %d test cases, got %d", len(want), len(suite.Cases))
+	}
+	for i, w := range want {
+		got := suite.Cases[i]
+		if got.Name != w.name || got.Classname != w.classname || got.Time != w.time {
+			t.Errorf("case %d: want %s/%s/%s, got %s/%s/%s", i, w.name, w.classname, w.time, got.Name, got.Classname, got.Time)
+		}
+		if (got.Failure != nil) != w.failed || (got.Skipped != nil) != w.skipped {
+			t.Errorf("case %s: want failed=%t skipped=%t, got %#v", w.name, w.failed, w.skipped, got)
+		}
+	}
+	if suite.Cases[1].Failure != nil && !strings.Contains(suite.Cases[1].Failure.Body, "expected plan to fail") {
+		t.Errorf("expected failure body to hold the test output, got %q", suite.Cases[1].Failure.Body)
+	}
+	if suite.Cases[2].Skipped != nil && suite.Cases[2].Skipped.Message != "versions_test.go:5: tofu not installed" {
+		t.Errorf("expected skip message to hold the reason, got %q", suite.Cases[2].Skipped.Message)
+	}
+}
+
+// TestRunReportsPackageFailures ensures a package that fails without a failing
+// test, such as a TestMain exit, still appears as a failed case.
+func TestRunReportsPackageFailures(t *testing.T) {
+	events := `{"Action":"start","Package":"example/terratest"}
+{"Action":"output","Package":"example/terratest","Output":"OpenTofu binary \"tofu\" not found\n"}
+{"Action":"fail","Package":"example/terratest","Elapsed":0.01}
+`
+	out := filepath.Join(t.TempDir(), "report.xml")
+
+	if code := run(strings.NewReader(events), &bytes.Buffer{}, out, writeModules(t)); code != 1 {
+		t.Fatalf("expected exit code 1, got %d", code)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(data, &suites); err != nil {
+		t.Fatalf("parse report: %v", err)
+	}
+	if suites.Failures != 1 || len(suites.Suites) != 1 || len(suites.Suites[0].Cases) != 1 {
+		t.Fatalf("expected a single package failure case, got %#v", suites)
+	}
+	if body := suites.Suites[0].Cases[0].Failure.Body; !strings.Contains(body, "not found") {
+		t.Fatalf("expected package output in the failure body, got %q", body)
+	}
+}
+
+// TestRunPassesWithoutReport checks a green stream exits zero and writes
+// nothing when CONCORDAT_JUNIT_OUT is unset.
+func TestRunPassesWithoutReport(t *testing.T) {
+	events := `{"Action":"run","Package":"example/terratest","Test":"TestTeamModulePermissionMap"}
+{"Action":"pass","Package":"example/terratest","Test":"TestTeamModulePermissionMap","Elapsed":0.1}
+{"Action":"pass","Package":"example/terratest","Elapsed":0.2}
+`
+	if code := run(strings.NewReader(events), &bytes.Buffer{}, "", "does-not-exist"); code != 0 {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+}
+
+// TestModuleForTestMatchesWordBoundaries covers multi-word modules, the
+// longest-match rule, and the suite fallback.
+func TestModuleForTestMatchesWordBoundaries(t *testing.T) {
+	modules := []string{"actions_permissions", "branch", "team"}
+	cases := map[string]string{
+		"TestActionsPermissionsRestrictsToSelected": "actions_permissions",
+		"TestBranchModuleRequiresStatusChecks/tofu": "branch",
+		"TestTeammateDirectory":                     "suite",
+		"TestParseTofuMatrix":                       "suite",
+	}
+	for name, want := range cases {
+		if got := moduleForTest(name, modules); got != want {
+			t.Errorf("moduleForTest(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,436 @@
+package terratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// fakeGitHubOwner is the organisation every fixture's provider block names.
+const fakeGitHubOwner = "platform"
+
+// fakeGitHub serves the slice of the GitHub REST API the provider touches
+// when it creates, reads, and updates a repository. Repositories are kept as
+// the JSON the provider sent, plus the fields GitHub computes, so reads echo
+// back what was written.
+type fakeGitHub struct {
+	mu    sync.Mutex
+	repos map[string]map[string]interface{}
+	// alerts records which repositories have vulnerability alerts enabled.
+	alerts map[string]bool
+	// unhandled lists requests the fake could not serve, so a failing apply
+	// can say which endpoint is missing.
+	unhandled []string
+}
+
+// startFakeGitHub starts the fake API and returns the environment that points
+// the GitHub provider at it. The provider appends api/v3/ to any base URL
+// other than api.github.com, so both prefixed and bare paths are served.
+func startFakeGitHub(t *testing.T) (*httptest.Server, map[string]string) {
+	t.Helper()
+
+	fake := &fakeGitHub{
+		repos:  map[string]map[string]interface{}{},
+		alerts: map[string]bool{},
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(func() {
+		fake.mu.Lock()
+		defer fake.mu.Unlock()
+		if len(fake.unhandled) > 0 {
+			t.Logf("fake GitHub could not serve: %s", strings.Join(fake.unhandled, ", "))
+		}
+	})
+
+	return server, map[string]string{
+		"GITHUB_BASE_URL": server.URL + "/",
+		"GITHUB_TOKEN":    "fake-github-token",
+	}
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+
+	switch {
+	case r.Method == http.MethodGet && len(parts) == 2 && parts[0] == "orgs":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"login": parts[1], "id": 1, "type": "Organization"})
+	case r.Method == http.MethodGet && len(parts) == 1 && parts[0] == "user":
+		writeJSON(w, http.StatusOK, map[string]interface{}{"login": "fake-user", "id": 2, "type": "User"})
+	case r.Method == http.MethodPost && len(parts) == 3 && parts[0] == "orgs" && parts[2] == "repos":
+		f.createRepository(w, r, parts[1])
+	case len(parts) >= 3 && parts[0] == "repos":
+		f.serveRepository(w, r, parts[1], parts[2], parts[3:])
+	default:
+		f.notHandled(w, r)
+	}
+}
+
+func (f *fakeGitHub) createRepository(w http.ResponseWriter, r *http.Request, owner string) {
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+		return
+	}
+	name, _ := body["name"].(string)
+	key := owner + "/" + name
+	if _, exists := f.repos[key]; exists {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": "name already exists on this account"})
+		return
+	}
+
+	repo := map[string]interface{}{
+		"id":             len(f.repos) + 1000,
+		"node_id":        fmt.Sprintf("R_fake%d", len(f.repos)+1),
+		"name":           name,
+		"full_name":      key,
+		"owner":          map[string]interface{}{"login": owner},
+		"html_url":       "https://github.com/" + key,
+		"default_branch": "main",
+		"topics":         []interface{}{},
+		"archived":       false,
+	}
+	mergeRepositoryFields(repo, body)
+	f.repos[key] = repo
+	writeJSON(w, http.StatusCreated, repo)
+}
+
+func (f *fakeGitHub) serveRepository(w http.ResponseWriter, r *http.Request, owner, name string, rest []string) {
+	key := owner + "/" + name
+	repo, exists := f.repos[key]
+	if !exists {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+
+	switch {
+	case len(rest) == 0 && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, repo)
+	case len(rest) == 0 && r.Method == http.MethodPatch:
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+		mergeRepositoryFields(repo, body)
+		writeJSON(w, http.StatusOK, repo)
+	case len(rest) == 0 && r.Method == http.MethodDelete:
+		delete(f.repos, key)
+		delete(f.alerts, key)
+		w.WriteHeader(http.StatusNoContent)
+	case len(rest) == 1 && rest[0] == "topics" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"names": repo["topics"]})
+	case len(rest) == 1 && rest[0] == "topics" && r.Method == http.MethodPut:
+		var body struct {
+			Names []interface{} `json:"names"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"message": err.Error()})
+			return
+		}
+		if body.Names == nil {
+			body.Names = []interface{}{}
+		}
+		repo["topics"] = body.Names
+		writeJSON(w, http.StatusOK, map[string]interface{}{"names": body.Names})
+	case len(rest) == 1 && rest[0] == "vulnerability-alerts":
+		switch r.Method {
+		case http.MethodPut:
+			f.alerts[key] = true
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			f.alerts[key] = false
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			if f.alerts[key] {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Vulnerability alerts are disabled."})
+		default:
+			f.notHandled(w, r)
+		}
+	default:
+		f.notHandled(w, r)
+	}
+}
+
+// mergeRepositoryFields copies request fields onto repo, translating the few
+// whose response name differs from the request name.
+func mergeRepositoryFields(repo, body map[string]interface{}) {
+	for field, value := range body {
+		repo[field] = value
+	}
+	if visibility, ok := body["visibility"].(string); ok {
+		repo["private"] = visibility != "public"
+	} else if private, ok := body["private"].(bool); ok {
+		repo["visibility"] = map[bool]string{true: "private", false: "public"}[private]
+	}
+}
+
+func (f *fakeGitHub) notHandled(w http.ResponseWriter, r *http.Request) {
+	f.unhandled = append(f.unhandled, r.Method+" "+r.URL.Path)
+	writeJSON(w, http.StatusNotFound, map[string]string{"message": "fake GitHub does not serve " + r.Method + " " + r.URL.Path})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// fakeGitHubRepository fetches a repository from the fake as the provider
+// would see it, failing the test when it does not exist.
+func fakeGitHubRepository(t *testing.T, server *httptest.Server, name string) map[string]interface{} {
+	t.Helper()
+
+	resp, err := server.Client().Get(server.URL + "/api/v3/repos/" + fakeGitHubOwner + "/" + name)
+	if err != nil {
+		t.Fatalf("read %s from fake GitHub: %v", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("read %s from fake GitHub: %s: %s", name, resp.Status, body)
+	}
+
+	var repo map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&repo); err != nil {
+		t.Fatalf("decode %s from fake GitHub: %v", name, err)
+	}
+	return repo
+}
+
+// TestFakeGitHubRoundTripsRepositories drives the fake with the requests the
+// provider makes, without tofu, so the apply test's dependency is covered.
+func TestFakeGitHubRoundTripsRepositories(t *testing.T) {
+	t.Parallel()
+
+	server, env := startFakeGitHub(t)
+	defer server.Close()
+	if env["GITHUB_BASE_URL"] != server.URL+"/" {
+		t.Fatalf("expected GITHUB_BASE_URL to point at the fake, got %q", env["GITHUB_BASE_URL"])
+	}
+
+	send := func(method, path, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("build %s %s: %v", method, path, err)
+		}
+		resp, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+
+	if resp := send(http.MethodGet, "/api/v3/orgs/platform", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the organisation lookup to succeed, got %s", resp.Status)
+	}
+	if resp := send(http.MethodPost, "/api/v3/orgs/platform/repos", `{"name":"demo","visibility":"private","delete_branch_on_merge":false}`); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected create to return 201, got %s", resp.Status)
+	}
+	if resp := send(http.MethodPost, "/orgs/platform/repos", `{"name":"demo"}`); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected a duplicate create to be refused, got %s", resp.Status)
+	}
+	send(http.MethodPatch, "/api/v3/repos/platform/demo", `{"delete_branch_on_merge":true}`)
+	send(http.MethodPut, "/api/v3/repos/platform/demo/topics", `{"names":["fixture"]}`)
+	send(http.MethodPut, "/api/v3/repos/platform/demo/vulnerability-alerts", "")
+
+	repo := fakeGitHubRepository(t, server, "demo")
+	assertBoolTrue(t, repo, "delete_branch_on_merge", "PATCH should update the stored repository")
+	assertBoolTrue(t, repo, "private", "visibility private should imply private")
+	assertStringSliceEquals(t, repo, "topics", []string{"fixture"}, "topics should round-trip")
+	if resp := send(http.MethodGet, "/api/v3/repos/platform/demo/vulnerability-alerts", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected enabled alerts to read back as 204, got %s", resp.Status)
+	}
+	if resp := send(http.MethodGet, "/api/v3/repos/platform/demo/pages", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected unknown endpoints to 404, got %s", resp.Status)
+	}
+	if resp := send(http.MethodDelete, "/api/v3/repos/platform/demo", ""); resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected delete to return 204, got %s", resp.Status)
+	}
+	if resp := send(http.MethodGet, "/api/v3/repos/platform/demo", ""); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected a deleted repository to 404, got %s", resp.Status)
+	}
+}
+
+// TestRepositoryModuleAppliesAgainstFakeGitHub applies the repository fixture
+// against the fake API, checks delete_branch_on_merge reached "GitHub", and
+// re-plans to prove the applied settings read back without drift.
+func TestRepositoryModuleAppliesAgainstFakeGitHub(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		server, env := startFakeGitHub(t)
+		defer server.Close()
+
+		moduleCopy := copyStackToTemp(t, filepath.Join("..", "modules", "repository"))
+		options := terraformOptions(t, binary, moduleCopy, "tests", "fixture")
+		for key, value := range env {
+			options.EnvVars[key] = value
+		}
+		planFile := options.PlanFilePath
+		// Apply directly rather than from a saved plan.
+		options.PlanFilePath = ""
+
+		if _, err := terraform.InitAndApplyE(t, options); err != nil {
+			t.Fatalf("apply repository fixture against fake GitHub: %v", err)
+		}
+
+		repo := fakeGitHubRepository(t, server, "fixture-repo")
+		assertBoolTrue(t, repo, "delete_branch_on_merge", "applied repository should delete branches on merge")
+
+		options.PlanFilePath = planFile
+		planStruct := tracedPlan(t, options)
+		assertNoDestroys(t, planStruct)
+		assertPlannedAction(t, planStruct, "module.repository.github_repository.this", "no-op")
+	})
+}
+
+// fakeS3BackendFile is written into a copied fixture so it keeps state in the
+// fake S3 server; the backend settings come from backendInitOptions.
+const fakeS3BackendFile = "fake_s3_backend.tofu"
+
+// TestRepositoryModuleIsIdempotent applies the repository fixture with state
+// in fake S3 and resources in fake GitHub, checks the state object in the
+// bucket records the repository, then re-plans from that remote state and
+// expects no changes, catching attributes the provider would rewrite on every
+// run. Unlike the apply test's local-state re-plan, this round-trips the
+// state through the S3 backend. Like
+// the apply test, it fails when the fake cannot serve an endpoint, which
+// startFakeGitHub logs.
+func TestRepositoryModuleIsIdempotent(t *testing.T) {
+	t.Parallel()
+
+	fakeS3, bucket, client := startFakeS3WithOptions(t, fakeS3Options{})
+	defer fakeS3.Close()
+	server, env := startFakeGitHub(t)
+	defer server.Close()
+
+	moduleCopy := copyStackToTemp(t, filepath.Join("..", "modules", "repository"))
+	fixture := filepath.Join(moduleCopy, "tests", "fixture")
+	backend := "terraform {\n  backend \"s3\" {}\n}\n"
+	if err := os.WriteFile(filepath.Join(fixture, fakeS3BackendFile), []byte(backend), 0o644); err != nil {
+		t.Fatalf("write backend block: %v", err)
+	}
+
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	options := backendInitOptions(t, fixture, config)
+	for key, value := range env {
+		options.EnvVars[key] = value
+	}
+	if _, err := terraform.InitAndApplyE(t, options); err != nil {
+		t.Fatalf("apply repository fixture: %v", err)
+	}
+
+	var state struct {
+		Resources []struct {
+			Module string `json:"module"`
+			Type   string `json:"type"`
+			Name   string `json:"name"`
+		} `json:"resources"`
+	}
+	stored := readFakeS3Object(t, client, bucket, config.Key)
+	if err := json.Unmarshal(stored, &state); err != nil {
+		t.Fatalf("decode state written to fake S3: %v", err)
+	}
+	recorded := false
+	for _, resource := range state.Resources {
+		if resource.Module == "module.repository" && resource.Type == "github_repository" && resource.Name == "this" {
+			recorded = true
+		}
+	}
+	if !recorded {
+		t.Fatalf("expected state at %s to record module.repository.github_repository.this, got %s", config.Key, stored)
+	}
+
+	options.PlanFilePath = planFilePath(t)
+	assertEmptyPlan(t, tracedPlan(t, options))
+}
+
+// compositeApplyTestFile is written into the composite copy so tofu test
+// applies the stack against a mocked provider and then destroys it.
+const compositeApplyTestFile = "apply_destroy.tftest.hcl"
+
+// compositeApplyTest mocks the GitHub provider, so every resource the
+// composite stack declares (repository, branch protection, team, team
+// memberships, and team repository grants) is created without an API.
+const compositeApplyTest = `mock_provider "github" {}
+
+run "apply" {
+  command = apply
+
+  assert {
+    condition     = module.repository.repository_name == "composite-repo"
+    error_message = "composite stack should create the repository"
+  }
+
+  assert {
+    condition     = module.team.repository_permissions["composite-repo"] == "push"
+    error_message = "team should be granted push on the composite repository"
+  }
+}
+`
+
+// TestCompositeStackAppliesAndDestroys applies the composite stack with tofu
+// test and a mocked GitHub provider. tofu test destroys what the run created
+// and fails when that teardown leaves anything in state, so a passing run
+// proves the destroy order works end to end. The repository module's
+// prevent_destroy is lifted in the scratch copy, since that guard exists to
+// stop exactly this teardown in production.
+func TestCompositeStackAppliesAndDestroys(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		stack := copyStackToTemp(t, filepath.Join("testdata", "composite"))
+		liftPreventDestroy(t, filepath.Join(stack, "..", "..", "..", "modules", "repository", "main.tofu"))
+		if err := os.WriteFile(filepath.Join(stack, compositeApplyTestFile), []byte(compositeApplyTest), 0o644); err != nil {
+			t.Fatalf("write composite apply test: %v", err)
+		}
+
+		options := terraformOptions(t, binary, stack)
+		if _, err := terraform.InitE(t, options); err != nil {
+			t.Fatalf("init composite stack: %v", err)
+		}
+		output, err := terraform.RunTerraformCommandE(t, options, "test", "-no-color")
+		if err != nil {
+			t.Fatalf("apply and destroy composite stack: %v\n%s", err, output)
+		}
+		if !strings.Contains(output, "1 passed, 0 failed") {
+			t.Fatalf("expected the apply run to pass and clean up, got:\n%s", output)
+		}
+	})
+}
+
+// liftPreventDestroy rewrites prevent_destroy = true to false in a copied
+// module file so an apply/destroy round trip can tear it down.
+func liftPreventDestroy(t *testing.T, path string) {
+	t.Helper()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", path, err)
+	}
+	lifted := strings.ReplaceAll(string(data), "prevent_destroy = true", "prevent_destroy = false")
+	if lifted == string(data) {
+		t.Fatalf("expected %s to set prevent_destroy = true", path)
+	}
+	if err := os.WriteFile(path, []byte(lifted), 0o644); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
@@ -0,0 +1,206 @@
+package terratest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// transientFailures fails the first N requests for chosen object keys with
+// 503 Service Unavailable, then lets them through. Counting is per key and
+// deterministic, so a test knows exactly how many retries the client needed.
+type transientFailures struct {
+	mu        sync.Mutex
+	remaining map[string]int
+	served    map[string]int
+	armed     atomic.Bool
+}
+
+// newTransientFailures builds an injector that fails each object key the
+// given number of times once armed.
+func newTransientFailures(failures map[string]int) *transientFailures {
+	remaining := make(map[string]int, len(failures))
+	for key, count := range failures {
+		remaining[key] = count
+	}
+	return &transientFailures{remaining: remaining, served: map[string]int{}}
+}
+
+// arm starts injecting failures. startFakeS3WithOptions arms the injector
+// after creating and seeding the bucket so setup requests are not counted.
+func (f *transientFailures) arm() {
+	f.armed.Store(true)
+}
+
+// wrap returns next with failure injection for objects in bucket.
+func (f *transientFailures) wrap(next http.Handler, bucket string) http.Handler {
+	prefix := "/" + bucket + "/"
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if f.armed.Load() && strings.HasPrefix(r.URL.Path, prefix) && f.take(strings.TrimPrefix(r.URL.Path, prefix)) {
+			http.Error(w, "injected transient failure", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take consumes one pending failure for key, reporting whether the request
+// should fail.
+func (f *transientFailures) take(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.remaining[key] == 0 {
+		return false
+	}
+	f.remaining[key]--
+	f.served[key]++
+	return true
+}
+
+// servedFor returns how many failures have been injected for key.
+func (f *transientFailures) servedFor(key string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.served[key]
+}
+
+// fakeS3StateLock manipulates the native S3 lockfile OpenTofu writes next to
+// the state object when use_lockfile is enabled.
+type fakeS3StateLock struct {
+	client *s3.S3
+	bucket string
+	key    string
+}
+
+// newFakeS3StateLock returns a lock client for the state at stateKey.
+func newFakeS3StateLock(client *s3.S3, bucket, stateKey string) *fakeS3StateLock {
+	return &fakeS3StateLock{client: client, bucket: bucket, key: stateKey + ".tflock"}
+}
+
+// acquire writes a lockfile on behalf of another operator, as if a second
+// tofu run were holding the state.
+func (l *fakeS3StateLock) acquire(t *testing.T, who string) {
+	t.Helper()
+
+	info, err := json.Marshal(map[string]string{
+		"ID":        "00000000-0000-0000-0000-000000000001",
+		"Operation": "OperationTypeApply",
+		"Who":       who,
+		"Version":   "1.10.7",
+		"Created":   time.Now().UTC().Format(time.RFC3339Nano),
+		"Path":      l.bucket + "/" + strings.TrimSuffix(l.key, ".tflock"),
+	})
+	if err != nil {
+		t.Fatalf("encode lock info: %v", err)
+	}
+	_, err = l.client.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(l.bucket),
+		Key:    aws.String(l.key),
+		Body:   bytes.NewReader(info),
+	})
+	if err != nil {
+		t.Fatalf("acquire state lock %s: %v", l.key, err)
+	}
+}
+
+// release removes the lockfile.
+func (l *fakeS3StateLock) release(t *testing.T) {
+	t.Helper()
+
+	if _, err := l.client.DeleteObject(&s3.DeleteObjectInput{Bucket: aws.String(l.bucket), Key: aws.String(l.key)}); err != nil {
+		t.Fatalf("release state lock %s: %v", l.key, err)
+	}
+}
+
+// held reports whether a lockfile currently exists.
+func (l *fakeS3StateLock) held(t *testing.T) bool {
+	t.Helper()
+
+	_, err := l.client.HeadObject(&s3.HeadObjectInput{Bucket: aws.String(l.bucket), Key: aws.String(l.key)})
+	if err == nil {
+		return true
+	}
+	if reqErr, ok := err.(awserr.RequestFailure); ok && reqErr.StatusCode() == http.StatusNotFound {
+		return false
+	}
+	t.Fatalf("check state lock %s: %v", l.key, err)
+	return false
+}
+
+// TestTransientFailuresFailsFirstRequests checks the middleware fails exactly
+// N requests to the chosen key, ignores other keys, and stays quiet until
+// armed.
+func TestTransientFailuresFailsFirstRequests(t *testing.T) {
+	failures := newTransientFailures(map[string]int{"state/terraform.tfstate": 2})
+	handler := failures.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), "bucket")
+
+	status := func(path string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder.Code
+	}
+
+	if got := status("/bucket/state/terraform.tfstate"); got != http.StatusOK {
+		t.Fatalf("expected requests before arm to pass, got %d", got)
+	}
+
+	failures.arm()
+	want := []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK, http.StatusOK}
+	for i, code := range want {
+		if got := status("/bucket/state/terraform.tfstate"); got != code {
+			t.Fatalf("request %d: expected %d, got %d", i+1, code, got)
+		}
+	}
+	if got := status("/bucket/other"); got != http.StatusOK {
+		t.Fatalf("expected other keys to pass, got %d", got)
+	}
+	if got := status("/other-bucket/state/terraform.tfstate"); got != http.StatusOK {
+		t.Fatalf("expected other buckets to pass, got %d", got)
+	}
+	if got := failures.servedFor("state/terraform.tfstate"); got != 2 {
+		t.Fatalf("expected two injected failures, got %d", got)
+	}
+}
+
+// TestFakeS3StateLockTracksLockfile checks the lock client writes, detects,
+// and removes the lockfile next to the state key.
+func TestFakeS3StateLockTracksLockfile(t *testing.T) {
+	server, bucket, client := startFakeS3WithOptions(t, fakeS3Options{})
+	defer server.Close()
+
+	lock := newFakeS3StateLock(client, bucket, fakeS3StateKey)
+	if lock.held(t) {
+		t.Fatalf("expected a fresh bucket to hold no lock")
+	}
+
+	lock.acquire(t, "other-operator@ci")
+	if !lock.held(t) {
+		t.Fatalf("expected lock to be held after acquire")
+	}
+	var info map[string]string
+	if err := json.Unmarshal(readFakeS3Object(t, client, bucket, fakeS3StateKey+".tflock"), &info); err != nil {
+		t.Fatalf("decode lock info: %v", err)
+	}
+	if info["Who"] != "other-operator@ci" {
+		t.Fatalf("expected lock info to name the holder, got %#v", info)
+	}
+
+	lock.release(t)
+	if lock.held(t) {
+		t.Fatalf("expected lock to be gone after release")
+	}
+}
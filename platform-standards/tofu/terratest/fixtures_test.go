@@ -0,0 +1,212 @@
+package terratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// fixtureManifestPath records, for every module fixture, whether it should
+// plan cleanly with its defaults and which tests exercise it.
+var fixtureManifestPath = filepath.Join("testdata", "fixtures.json")
+
+// rejectFixturePrefix marks fixtures that must fail to plan. TestRejectFixtures
+// discovers them by name, so a new negative fixture needs no test of its own.
+const rejectFixturePrefix = "fixture_reject_"
+
+// fixtureExpectation is one manifest entry, keyed by "<module>/<fixture>".
+// Expect is "plan" when the fixture plans cleanly with its own defaults and
+// "reject" when a guardrail should stop the plan.
+type fixtureExpectation struct {
+	Expect string   `json:"expect"`
+	Tests  []string `json:"tests"`
+}
+
+// loadFixtureManifest reads and decodes the fixture manifest.
+func loadFixtureManifest(t *testing.T) map[string]fixtureExpectation {
+	t.Helper()
+
+	raw, err := os.ReadFile(repoPath(t, fixtureManifestPath))
+	if err != nil {
+		t.Fatalf("read fixture manifest: %v", err)
+	}
+	var manifest map[string]fixtureExpectation
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		t.Fatalf("decode fixture manifest %s: %v", fixtureManifestPath, err)
+	}
+	return manifest
+}
+
+// moduleFixtures lists every fixture directory under modules/*/tests as
+// "<module>/<fixture>".
+func moduleFixtures(t *testing.T) []string {
+	t.Helper()
+
+	var fixtures []string
+	for _, dir := range moduleDirs(t) {
+		entries, err := os.ReadDir(filepath.Join(dir, "tests"))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			t.Fatalf("list fixtures in %s: %v", dir, err)
+		}
+		for _, entry := range entries {
+			if entry.IsDir() {
+				fixtures = append(fixtures, filepath.Base(dir)+"/"+entry.Name())
+			}
+		}
+	}
+	sort.Strings(fixtures)
+	return fixtures
+}
+
+// testFixtureLiterals maps each Test function in this package to the string
+// literals its body mentions, so the manifest can be checked against the
+// code rather than trusted.
+func testFixtureLiterals(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	files, err := filepath.Glob(repoPath(t, "*_test.go"))
+	if err != nil {
+		t.Fatalf("glob test files: %v", err)
+	}
+	fset := token.NewFileSet()
+	literals := map[string]map[string]bool{}
+	for _, file := range files {
+		parsed, err := parser.ParseFile(fset, file, nil, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", file, err)
+		}
+		for _, decl := range parsed.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv != nil || !strings.HasPrefix(fn.Name.Name, "Test") {
+				continue
+			}
+			seen := map[string]bool{}
+			ast.Inspect(fn, func(node ast.Node) bool {
+				if lit, ok := node.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					if value, err := strconv.Unquote(lit.Value); err == nil {
+						seen[value] = true
+					}
+				}
+				return true
+			})
+			literals[fn.Name.Name] = seen
+		}
+	}
+	return literals
+}
+
+// TestEveryFixtureHasCoverage fails when a fixture directory is missing from
+// the manifest, when the manifest names a fixture that no longer exists, or
+// when a listed test does not exist or never mentions its fixture.
+func TestEveryFixtureHasCoverage(t *testing.T) {
+	t.Parallel()
+
+	manifest := loadFixtureManifest(t)
+	literals := testFixtureLiterals(t)
+
+	onDisk := map[string]bool{}
+	for _, fixture := range moduleFixtures(t) {
+		onDisk[fixture] = true
+		if _, ok := manifest[fixture]; !ok {
+			t.Errorf("fixture %s has no entry in %s; add one naming the tests that cover it", fixture, fixtureManifestPath)
+		}
+	}
+
+	for fixture, entry := range manifest {
+		if !onDisk[fixture] {
+			t.Errorf("%s lists %s, but no such fixture directory exists", fixtureManifestPath, fixture)
+			continue
+		}
+		if entry.Expect != "plan" && entry.Expect != "reject" {
+			t.Errorf("fixture %s: expect must be \"plan\" or \"reject\", got %q", fixture, entry.Expect)
+		}
+		if len(entry.Tests) == 0 {
+			t.Errorf("fixture %s lists no tests", fixture)
+		}
+		name := filepath.Base(fixture)
+		if strings.HasPrefix(name, rejectFixturePrefix) && entry.Expect != "reject" {
+			t.Errorf("fixture %s is named as a reject fixture but expects %q", fixture, entry.Expect)
+		}
+		for _, test := range entry.Tests {
+			if test == "TestRejectFixtures" {
+				if !strings.HasPrefix(name, rejectFixturePrefix) {
+					t.Errorf("fixture %s names TestRejectFixtures, which only runs %s* fixtures", fixture, rejectFixturePrefix)
+				}
+				continue
+			}
+			seen, ok := literals[test]
+			if !ok {
+				t.Errorf("fixture %s names %s, which is not a test in this package", fixture, test)
+				continue
+			}
+			if !seen[name] {
+				t.Errorf("fixture %s names %s, but that test never refers to %q", fixture, test, name)
+			}
+		}
+	}
+}
+
+// planRejected plans the fixture at dir and returns an error when the plan
+// succeeds, since reject fixtures exist to trip a guardrail.
+func planRejected(t *testing.T, binary, dir string) error {
+	t.Helper()
+
+	options := terraformOptions(t, binary, dir)
+	if _, err := terraform.InitAndPlanE(t, options); err == nil {
+		return fmt.Errorf("fixture %s planned cleanly; %s* fixtures must fail to plan", dir, rejectFixturePrefix)
+	}
+	return nil
+}
+
+// TestRejectFixtures plans every modules/*/tests/fixture_reject_* directory
+// and expects each plan to fail.
+func TestRejectFixtures(t *testing.T) {
+	t.Parallel()
+
+	dirs, err := filepath.Glob(repoPath(t, "..", "modules", "*", "tests", rejectFixturePrefix+"*"))
+	if err != nil {
+		t.Fatalf("glob reject fixtures: %v", err)
+	}
+	if len(dirs) == 0 {
+		t.Fatalf("expected at least one %s* fixture", rejectFixturePrefix)
+	}
+
+	for _, dir := range dirs {
+		name := filepath.Base(filepath.Dir(filepath.Dir(dir))) + "/" + filepath.Base(dir)
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			forEachTofu(t, func(t *testing.T, binary string) {
+				if err := planRejected(t, binary, dir); err != nil {
+					t.Fatal(err)
+				}
+			})
+		})
+	}
+}
+
+// TestRejectFixturesCatchesCleanPlan is the positive control: pointed at a
+// fixture that plans cleanly, the harness must report a failure.
+func TestRejectFixturesCatchesCleanPlan(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		dir := filepath.Join("..", "modules", "repository", "tests", "fixture")
+		if err := planRejected(t, binary, dir); err == nil {
+			t.Fatalf("expected planRejected to flag %s, which plans cleanly", dir)
+		}
+	})
+}
@@ -0,0 +1,89 @@
+package terratest
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// fmtDriftExitCode is what fmt -check exits with when it found files to
+// rewrite; any other non-zero exit means it could not check them.
+const fmtDriftExitCode = 3
+
+// unformattedSpecimen is deliberately misformatted HCL. Its extension keeps
+// it out of the repository-wide check until a test copies it into place.
+var unformattedSpecimen = filepath.Join("testdata", "unformatted", "main.tofu.specimen")
+
+// unformattedFiles runs fmt -check -recursive under dir and returns the files
+// it would rewrite, relative to dir and sorted.
+func unformattedFiles(t *testing.T, binary, dir string) ([]string, error) {
+	t.Helper()
+
+	options := baseOptions(t, binary, dir)
+	options.PlanFilePath = ""
+	stdout, err := terraform.RunTerraformCommandAndGetStdoutE(t, options, terraform.FormatArgs(options, "fmt", "-check", "-recursive", "-list=true")...)
+	if err != nil {
+		code, codeErr := shell.GetExitCodeForRunCommandError(err)
+		if codeErr != nil || code != fmtDriftExitCode {
+			return nil, err
+		}
+	}
+
+	var files []string
+	for _, line := range strings.Split(stdout, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, filepath.Clean(line))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// TestTerraformFormatting fails when any committed HCL under the tofu tree
+// differs from fmt output, listing the files to reformat.
+func TestTerraformFormatting(t *testing.T) {
+	t.Parallel()
+	requireTofu(t)
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		files, err := unformattedFiles(t, binary, repoPath(t, ".."))
+		if err != nil {
+			t.Fatalf("fmt -check: %v", err)
+		}
+
+		if len(files) > 0 {
+			t.Fatalf("files need tofu fmt:\n  %s", strings.Join(files, "\n  "))
+		}
+	})
+}
+
+// TestUnformattedFilesDetectsDrift points the check at the misformatted
+// specimen and expects it to be reported.
+func TestUnformattedFilesDetectsDrift(t *testing.T) {
+	t.Parallel()
+	requireTofu(t)
+
+	specimen, err := os.ReadFile(repoPath(t, unformattedSpecimen))
+	if err != nil {
+		t.Fatalf("read specimen: %v", err)
+	}
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "main.tofu"), specimen, 0o644); err != nil {
+			t.Fatalf("write specimen: %v", err)
+		}
+		files, err := unformattedFiles(t, binary, dir)
+		if err != nil {
+			t.Fatalf("fmt -check: %v", err)
+		}
+		if len(files) != 1 || files[0] != "main.tofu" {
+			t.Fatalf("expected main.tofu to be reported as unformatted, got %q", files)
+		}
+	})
+}
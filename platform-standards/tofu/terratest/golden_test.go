@@ -0,0 +1,530 @@
+package terratest
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// update rewrites every golden file from the current output instead of
+// comparing against it. All golden assertions go through assertGolden, so
+// "go test . -update" regenerates them in one run; add -run TestName to
+// regenerate just one. The flag is only registered in this package, so it
+// must not be passed to ./..., whose other packages would reject it.
+var update = flag.Bool("update", false, "rewrite golden files with the current output")
+
+// volatileAttributes are provider-computed values that differ between runs and
+// so never belong in a golden file.
+var volatileAttributes = map[string]bool{
+	"id":      true,
+	"etag":    true,
+	"node_id": true,
+	"repo_id": true,
+}
+
+// assertPlanMatchesGolden compares the normalised plan with the golden file at
+// goldenPath, rewriting the file instead when -update is set.
+func assertPlanMatchesGolden(t *testing.T, plan *terraform.PlanStruct, goldenPath string) {
+	t.Helper()
+
+	got, err := normalizePlan(plan)
+	if err != nil {
+		t.Fatalf("normalise plan: %v", err)
+	}
+	assertGolden(t, goldenPath, got)
+}
+
+// assertGolden fails the test unless got matches the golden file at path,
+// rewriting the file instead when -update is set.
+func assertGolden(t *testing.T, path string, got []byte) {
+	t.Helper()
+
+	if err := compareGolden(path, got, *update); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// compareGolden is assertGolden without the test: it rewrites path with got
+// when rewrite is set and otherwise reports any difference.
+func compareGolden(path string, got []byte, rewrite bool) error {
+	if rewrite {
+		return updateGolden(path, got)
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read golden %s (run with -update to create it): %w", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("output differs from %s; rerun with -update and review the diff\ngot:\n%s", path, got)
+	}
+	return nil
+}
+
+// updateGolden writes data to the golden file at path, creating its
+// directory as needed.
+func updateGolden(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create golden dir: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func isVolatileAttribute(key string) bool {
+	return volatileAttributes[key] || strings.HasSuffix(key, "_at")
+}
+
+func isUnsetValue(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(typed) == 0
+	case map[string]interface{}:
+		return len(typed) == 0
+	default:
+		return false
+	}
+}
+
+// unknownValueToken stands in for every value the plan marks as known after
+// apply, whatever the provider would eventually compute.
+const unknownValueToken = "(known after apply)"
+
+// normalizePlan renders the plan's resource changes as stable JSON: one entry
+// per address with its actions and after values, known-after-apply values
+// replaced by unknownValueToken, and volatile attributes such as ids and
+// *_at timestamps dropped along with unset values, so goldens track module
+// behaviour rather than optional attributes a provider release may add.
+// Plan-level fields like timestamp and the tool version are left out
+// entirely. Golden files and diffPlans both compare this form.
+func normalizePlan(plan *terraform.PlanStruct) ([]byte, error) {
+	normalized := make(map[string]interface{}, len(plan.ResourceChangesMap))
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change == nil {
+			continue
+		}
+		actions := make([]string, len(change.Change.Actions))
+		for i, action := range change.Change.Actions {
+			actions[i] = string(action)
+		}
+		// An empty after_unknown still routes after through the object case,
+		// so volatile and unset attributes are dropped either way.
+		unknown := change.Change.AfterUnknown
+		if unknown == nil {
+			unknown = map[string]interface{}{}
+		}
+		normalized[address] = map[string]interface{}{
+			"actions": actions,
+			"after":   mergeUnknownValues(change.Change.After, unknown),
+		}
+	}
+
+	// encoding/json sorts map keys, which keeps the output stable.
+	data, err := json.MarshalIndent(normalized, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// mergeUnknownValues overlays a plan's after_unknown structure on its after
+// value, substituting unknownValueToken wherever after_unknown is true.
+func mergeUnknownValues(value, unknown interface{}) interface{} {
+	switch marks := unknown.(type) {
+	case bool:
+		if marks {
+			return unknownValueToken
+		}
+		return value
+	case map[string]interface{}:
+		object, _ := value.(map[string]interface{})
+		merged := make(map[string]interface{}, len(object))
+		for key, item := range object {
+			if !isVolatileAttribute(key) && !isUnsetValue(item) {
+				merged[key] = item
+			}
+		}
+		for key, mark := range marks {
+			if isVolatileAttribute(key) {
+				continue
+			}
+			if item := mergeUnknownValues(object[key], mark); !isUnsetValue(item) {
+				merged[key] = item
+			} else {
+				delete(merged, key)
+			}
+		}
+		return merged
+	case []interface{}:
+		list, _ := value.([]interface{})
+		length := len(list)
+		if len(marks) > length {
+			length = len(marks)
+		}
+		merged := make([]interface{}, length)
+		for i := range merged {
+			var item, mark interface{}
+			if i < len(list) {
+				item = list[i]
+			}
+			if i < len(marks) {
+				mark = marks[i]
+			}
+			merged[i] = mergeUnknownValues(item, mark)
+		}
+		return merged
+	default:
+		return value
+	}
+}
+
+// normalizedChange is one address of normalizePlan's output.
+type normalizedChange struct {
+	Actions []string               `json:"actions"`
+	After   map[string]interface{} `json:"after"`
+}
+
+// diffPlans describes how newPlan differs from oldPlan once both are
+// normalised, one line per added or removed resource and per changed after
+// value, sorted by address. It returns "" when the plans agree.
+func diffPlans(oldPlan, newPlan *terraform.PlanStruct) (string, error) {
+	before, err := decodeNormalizedPlan(oldPlan)
+	if err != nil {
+		return "", err
+	}
+	after, err := decodeNormalizedPlan(newPlan)
+	if err != nil {
+		return "", err
+	}
+
+	addresses := map[string]bool{}
+	for address := range before {
+		addresses[address] = true
+	}
+	for address := range after {
+		addresses[address] = true
+	}
+	sorted := make([]string, 0, len(addresses))
+	for address := range addresses {
+		sorted = append(sorted, address)
+	}
+	sort.Strings(sorted)
+
+	var lines []string
+	for _, address := range sorted {
+		oldChange, inOld := before[address]
+		newChange, inNew := after[address]
+		switch {
+		case !inOld:
+			lines = append(lines, "+ "+address)
+		case !inNew:
+			lines = append(lines, "- "+address)
+		default:
+			lines = append(lines, diffAttributes(address, oldChange.After, newChange.After)...)
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// decodeNormalizedPlan runs plan through normalizePlan and decodes the result
+// by address.
+func decodeNormalizedPlan(plan *terraform.PlanStruct) (map[string]normalizedChange, error) {
+	data, err := normalizePlan(plan)
+	if err != nil {
+		return nil, fmt.Errorf("normalise plan: %w", err)
+	}
+	var changes map[string]normalizedChange
+	if err := json.Unmarshal(data, &changes); err != nil {
+		return nil, fmt.Errorf("decode normalised plan: %w", err)
+	}
+	return changes, nil
+}
+
+// diffAttributes reports each attribute of address whose normalised value
+// differs between before and after; a missing attribute reads as null.
+func diffAttributes(address string, before, after map[string]interface{}) []string {
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+
+	var lines []string
+	for _, key := range sorted {
+		oldValue, newValue := renderDiffValue(before[key]), renderDiffValue(after[key])
+		if oldValue != newValue {
+			lines = append(lines, fmt.Sprintf("~ %s.%s: %s -> %s", address, key, oldValue, newValue))
+		}
+	}
+	return lines
+}
+
+// renderDiffValue prints value as compact JSON.
+func renderDiffValue(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+// TestDiffPlansReportsChanges covers added, removed, and changed resources on
+// synthetic plans, and checks identical plans produce no diff.
+func TestDiffPlansReportsChanges(t *testing.T) {
+	t.Parallel()
+
+	oldPlan := parseSyntheticPlan(t, `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "github_repository.this", "mode": "managed", "type": "github_repository", "name": "this",
+     "change": {"actions": ["create"], "after": {"name": "demo", "has_wiki": false, "etag": "a", "topics": []}}},
+    {"address": "github_team.old", "mode": "managed", "type": "github_team", "name": "old",
+     "change": {"actions": ["create"], "after": {"name": "old"}}}
+  ]
+}`)
+	newPlan := parseSyntheticPlan(t, `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {"address": "github_repository.this", "mode": "managed", "type": "github_repository", "name": "this",
+     "change": {"actions": ["create"], "after": {"name": "demo", "has_wiki": true, "etag": "b"}}},
+    {"address": "github_team.new", "mode": "managed", "type": "github_team", "name": "new",
+     "change": {"actions": ["create"], "after": {"name": "new"}}}
+  ]
+}`)
+
+	want := strings.Join([]string{
+		"~ github_repository.this.has_wiki: false -> true",
+		"+ github_team.new",
+		"- github_team.old",
+	}, "\n")
+	if got, err := diffPlans(oldPlan, newPlan); err != nil || got != want {
+		t.Fatalf("unexpected diff (%v):\n%s\nwant:\n%s", err, got, want)
+	}
+	if got, err := diffPlans(oldPlan, oldPlan); err != nil || got != "" {
+		t.Fatalf("expected identical plans to produce no diff (%v), got:\n%s", err, got)
+	}
+}
+
+// TestDiffPlansAcrossFixtures plans two repository fixtures that differ only
+// in has_wiki and checks the diff names that attribute alone.
+func TestDiffPlansAcrossFixtures(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		baseline := tracedPlan(t, terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_merge_commit_messages"))
+		changed := tracedPlan(t, terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_wiki"))
+
+		diff, err := diffPlans(baseline, changed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("plan diff:\n%s", diff)
+		want := "~ module.repository.github_repository.this.has_wiki: false -> true"
+		if diff != want {
+			t.Fatalf("expected the diff to be %q, got:\n%s", want, diff)
+		}
+	})
+}
+
+// TestPlanGoldenUpdateAndCompare runs a synthetic plan through the same
+// normalise-then-compare steps as assertPlanMatchesGolden, in update mode and
+// then in normal mode, so the path is covered without tofu.
+func TestPlanGoldenUpdateAndCompare(t *testing.T) {
+	plan := parseSyntheticPlan(t, `{
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "github_repository.this",
+      "mode": "managed",
+      "type": "github_repository",
+      "name": "this",
+      "change": {
+        "actions": ["create"],
+        "after": {
+          "name": "golden",
+          "allow_squash_merge": true,
+          "etag": "W/\"abc\"",
+          "updated_at": "2024-01-01T00:00:00Z",
+          "description": null,
+          "pages": []
+        },
+        "after_unknown": {"id": true, "html_url": true}
+      }
+    }
+  ]
+}`)
+	goldenPath := filepath.Join(t.TempDir(), "golden", "plan.json")
+	checkPlanGolden := func(rewrite bool) error {
+		got, err := normalizePlan(plan)
+		if err != nil {
+			t.Fatalf("normalise plan: %v", err)
+		}
+		return compareGolden(goldenPath, got, rewrite)
+	}
+
+	if err := checkPlanGolden(true); err != nil {
+		t.Fatalf("update golden: %v", err)
+	}
+	written, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read written golden: %v", err)
+	}
+	for _, dropped := range []string{"etag", "updated_at", "description", "pages"} {
+		if bytes.Contains(written, []byte(dropped)) {
+			t.Fatalf("expected %s to be normalised away, got %s", dropped, written)
+		}
+	}
+
+	if err := checkPlanGolden(false); err != nil {
+		t.Fatalf("compare against freshly written golden: %v", err)
+	}
+
+	after := plan.ResourceChangesMap["github_repository.this"].Change.After.(map[string]interface{})
+	after["allow_squash_merge"] = false
+	if err := checkPlanGolden(false); err == nil {
+		t.Fatalf("expected comparison to fail after a planned value changed")
+	}
+}
+
+// TestCompareGoldenUpdatesThenCompares checks the shared helper writes the
+// file in update mode and then holds later runs to it.
+func TestCompareGoldenUpdatesThenCompares(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "output.txt")
+
+	if err := compareGolden(path, []byte("first\n"), false); err == nil {
+		t.Fatalf("expected a missing golden file to fail the comparison")
+	}
+	if err := compareGolden(path, []byte("first\n"), true); err != nil {
+		t.Fatalf("update golden: %v", err)
+	}
+	if written, err := os.ReadFile(path); err != nil || string(written) != "first\n" {
+		t.Fatalf("expected update to write the output, got %q, %v", written, err)
+	}
+	if err := compareGolden(path, []byte("first\n"), false); err != nil {
+		t.Fatalf("compare against freshly written golden: %v", err)
+	}
+	if err := compareGolden(path, []byte("second\n"), false); err == nil {
+		t.Fatalf("expected changed output to fail the comparison")
+	}
+}
+
+// TestNormalizePlanIsStable feeds the same plan twice, serialised in a
+// different order with a different timestamp, and expects identical output
+// with unknown values tokenised and volatile attributes dropped.
+func TestNormalizePlanIsStable(t *testing.T) {
+	first := parseSyntheticPlan(t, `{
+  "format_version": "1.2",
+  "timestamp": "2026-01-01T00:00:00Z",
+  "resource_changes": [
+    {
+      "address": "github_repository.this",
+      "mode": "managed",
+      "type": "github_repository",
+      "name": "this",
+      "change": {
+        "actions": ["create"],
+        "after": {"name": "stable", "topics": ["fixture"], "pages": [{"source": [{}]}]},
+        "after_unknown": {"id": true, "node_id": true, "created_at": true, "html_url": true, "pages": [{"url": true, "source": [{"branch": true}]}]}
+      }
+    },
+    {
+      "address": "github_team.this",
+      "mode": "managed",
+      "type": "github_team",
+      "name": "this",
+      "change": {
+        "actions": ["create"],
+        "after": {"privacy": "closed", "name": "platform"},
+        "after_unknown": {"slug": true}
+      }
+    }
+  ]
+}`)
+	second := parseSyntheticPlan(t, `{
+  "timestamp": "2026-06-30T12:34:56Z",
+  "format_version": "1.2",
+  "resource_changes": [
+    {
+      "address": "github_team.this",
+      "mode": "managed",
+      "type": "github_team",
+      "name": "this",
+      "change": {
+        "after_unknown": {"slug": true},
+        "after": {"name": "platform", "privacy": "closed"},
+        "actions": ["create"]
+      }
+    },
+    {
+      "address": "github_repository.this",
+      "mode": "managed",
+      "type": "github_repository",
+      "name": "this",
+      "change": {
+        "after_unknown": {"pages": [{"source": [{"branch": true}], "url": true}], "html_url": true, "created_at": true, "node_id": true, "id": true},
+        "after": {"topics": ["fixture"], "pages": [{"source": [{}]}], "name": "stable"},
+        "actions": ["create"]
+      }
+    }
+  ]
+}`)
+
+	got, err := normalizePlan(first)
+	if err != nil {
+		t.Fatalf("normalise first plan: %v", err)
+	}
+	again, err := normalizePlan(second)
+	if err != nil {
+		t.Fatalf("normalise second plan: %v", err)
+	}
+	if !bytes.Equal(got, again) {
+		t.Fatalf("expected identical output for equivalent plans\nfirst:\n%s\nsecond:\n%s", got, again)
+	}
+
+	var decoded map[string]map[string]interface{}
+	if err := json.Unmarshal(got, &decoded); err != nil {
+		t.Fatalf("decode normalised plan: %v", err)
+	}
+	after := decoded["github_repository.this"]["after"].(map[string]interface{})
+	if after["html_url"] != unknownValueToken {
+		t.Fatalf("expected html_url to be tokenised, got %#v", after["html_url"])
+	}
+	source := after["pages"].([]interface{})[0].(map[string]interface{})["source"].([]interface{})[0].(map[string]interface{})
+	if source["branch"] != unknownValueToken {
+		t.Fatalf("expected nested unknowns to be tokenised, got %#v", source)
+	}
+	for _, volatile := range []string{"id", "node_id", "created_at"} {
+		if _, ok := after[volatile]; ok {
+			t.Fatalf("expected volatile attribute %s to be dropped, got %#v", volatile, after)
+		}
+	}
+	if bytes.Contains(got, []byte("2026")) {
+		t.Fatalf("expected plan timestamps to be left out, got %s", got)
+	}
+}
+
+func parseSyntheticPlan(t *testing.T, planJSON string) *terraform.PlanStruct {
+	t.Helper()
+
+	plan, err := terraform.ParsePlanJSON(planJSON)
+	if err != nil {
+		t.Fatalf("parse synthetic plan: %v", err)
+	}
+	return plan
+}
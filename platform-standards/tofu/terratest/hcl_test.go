@@ -0,0 +1,110 @@
+package terratest
+
+import (
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// findBlock returns the first block in body of the given type whose labels
+// start with labels, or nil when none matches.
+func findBlock(body *hclsyntax.Body, blockType string, labels ...string) *hclsyntax.Block {
+	for _, blk := range body.Blocks {
+		if blk.Type != blockType || len(blk.Labels) < len(labels) {
+			continue
+		}
+		matched := true
+		for i, label := range labels {
+			if blk.Labels[i] != label {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return blk
+		}
+	}
+	return nil
+}
+
+// findAttributeString evaluates a literal string attribute on block, failing
+// the test when it is missing or not a string.
+func findAttributeString(t *testing.T, block *hclsyntax.Block, name string) string {
+	t.Helper()
+
+	attr, ok := block.Body.Attributes[name]
+	if !ok {
+		t.Fatalf("expected %s block to declare %s", block.Type, name)
+	}
+	value, diags := attr.Expr.Value(&hcl.EvalContext{})
+	if diags.HasErrors() {
+		t.Fatalf("evaluate %s.%s: %s", block.Type, name, diags.Error())
+	}
+	if value.IsNull() || !value.Type().Equals(cty.String) {
+		t.Fatalf("expected %s.%s to be a string, got %s", block.Type, name, value.Type().FriendlyName())
+	}
+	return value.AsString()
+}
+
+// parseInlineHCL parses an HCL snippet for helper tests.
+func parseInlineHCL(t *testing.T, src string) *hclsyntax.Body {
+	t.Helper()
+
+	file, diags := hclsyntax.ParseConfig([]byte(src), "inline.tofu", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("parse inline HCL: %s", diags.Error())
+	}
+	return file.Body.(*hclsyntax.Body)
+}
+
+// TestFindBlockMatchesTypeAndLabels covers label prefixes, misses, and
+// unlabelled lookups.
+func TestFindBlockMatchesTypeAndLabels(t *testing.T) {
+	body := parseInlineHCL(t, `
+terraform {
+  backend "s3" {}
+}
+
+resource "github_repository" "other" {}
+resource "github_repository" "this" {}
+`)
+
+	terraformBlock := findBlock(body, "terraform")
+	if terraformBlock == nil {
+		t.Fatalf("expected terraform block to be found")
+	}
+	if findBlock(terraformBlock.Body, "backend", "s3") == nil {
+		t.Fatalf("expected backend \"s3\" block to be found")
+	}
+	if blk := findBlock(terraformBlock.Body, "backend", "gcs"); blk != nil {
+		t.Fatalf("expected no backend \"gcs\" block, got %v", blk.Labels)
+	}
+
+	repo := findBlock(body, "resource", "github_repository", "this")
+	if repo == nil || repo.Labels[1] != "this" {
+		t.Fatalf("expected github_repository.this to be found, got %#v", repo)
+	}
+	if first := findBlock(body, "resource", "github_repository"); first == nil || first.Labels[1] != "other" {
+		t.Fatalf("expected a label prefix to return the first match, got %#v", first)
+	}
+	if findBlock(body, "module") != nil {
+		t.Fatalf("expected no module block")
+	}
+}
+
+// TestFindAttributeStringReadsLiterals checks a literal string attribute is
+// returned as-is.
+func TestFindAttributeStringReadsLiterals(t *testing.T) {
+	body := parseInlineHCL(t, `
+terraform {
+  required_version = ">= 1.10.7, < 2.0.0"
+}
+`)
+
+	got := findAttributeString(t, findBlock(body, "terraform"), "required_version")
+	if got != ">= 1.10.7, < 2.0.0" {
+		t.Fatalf("expected required_version literal, got %q", got)
+	}
+}
@@ -0,0 +1,241 @@
+// Package backendcheck holds the rules committed tfbackend files and the
+// backend.tf terraform block must follow, shared by the terratest suite and
+// cmd/backendcheck.
+package backendcheck
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendconfig"
+)
+
+const (
+	// ScalewayBucket is the Object Storage bucket every Scaleway estate uses.
+	ScalewayBucket = "df12-tfstate"
+	// ScalewayRegion is the region that bucket lives in.
+	ScalewayRegion = "fr-par"
+)
+
+// KeyPattern is the estates/<estate>/<stack>/terraform.tfstate layout every
+// state key follows.
+var KeyPattern = regexp.MustCompile(`^estates/[a-z0-9-]+/[a-z0-9-]+/terraform\.tfstate$`)
+
+// Scaleway runs every Scaleway backend rule against cfg and returns one
+// error per rule it breaks, in a stable order.
+func Scaleway(cfg backendconfig.Config) []error {
+	var errs []error
+	errs = append(errs, ScalewayRequiredFields(cfg)...)
+	errs = append(errs, ScalewayRequiredBooleans(cfg)...)
+	errs = append(errs, ScalewayForbiddenCredentials(cfg)...)
+	errs = append(errs, ScalewayOptionalSkipFlags(cfg)...)
+	errs = append(errs, EncryptionKept(cfg)...)
+	return append(errs, ProfileOmitted(cfg)...)
+}
+
+// AWS runs every rule for a genuine AWS backend against cfg.
+func AWS(cfg backendconfig.Config) []error {
+	var errs []error
+	errs = append(errs, AWSRequiredFields(cfg)...)
+	errs = append(errs, AWSForbiddenCredentials(cfg)...)
+	errs = append(errs, EncryptionKept(cfg)...)
+	return append(errs, ProfileOmitted(cfg)...)
+}
+
+// ScalewayRequiredFields checks the bucket, region, state key, and s3
+// endpoint of a Scaleway backend.
+func ScalewayRequiredFields(cfg backendconfig.Config) []error {
+	var errs []error
+	if cfg.Bucket != ScalewayBucket {
+		errs = append(errs, fmt.Errorf("unexpected bucket %q", cfg.Bucket))
+	}
+	errs = appendIf(errs, KeyViolation(cfg.Key))
+	if cfg.Region != ScalewayRegion {
+		errs = append(errs, fmt.Errorf("unexpected region %q", cfg.Region))
+	}
+	errs = appendIf(errs, ScalewayRegionEndpointViolation(cfg))
+	return appendIf(errs, ScalewayEndpointViolation(cfg))
+}
+
+// ScalewayRequiredBooleans checks the flags that stop the S3 backend
+// probing AWS-only APIs Scaleway does not serve.
+func ScalewayRequiredBooleans(cfg backendconfig.Config) []error {
+	var errs []error
+	for _, flag := range []struct {
+		set     bool
+		message string
+	}{
+		{cfg.UsePathStyle, "use_path_style must be true for Scaleway"},
+		{cfg.SkipRegionValidation, "skip_region_validation must be true to avoid AWS region probes"},
+		{cfg.SkipRequestingAccountID, "skip_requesting_account_id must prevent AWS-specific API calls"},
+		{cfg.SkipCredentialsValidation, "skip_credentials_validation avoids credentials lookups"},
+	} {
+		if !flag.set {
+			errs = append(errs, fmt.Errorf("%s, got %#v", flag.message, flag.set))
+		}
+	}
+	return errs
+}
+
+// ScalewayForbiddenCredentials rejects inline credentials and the AWS
+// locking settings Scaleway cannot honour.
+func ScalewayForbiddenCredentials(cfg backendconfig.Config) []error {
+	var errs []error
+	if cfg.UseLockfile != nil && *cfg.UseLockfile {
+		errs = append(errs, errors.New("use_lockfile should be omitted for Scaleway backends"))
+	}
+	errs = appendIf(errs, InlineCredentialViolation(cfg))
+	if cfg.DynamodbTable != nil {
+		errs = append(errs, errors.New("backend config should not declare DynamoDB locking"))
+	}
+	return errs
+}
+
+// ScalewayOptionalSkipFlags allows the remaining skip flags to be omitted
+// but not set to false.
+func ScalewayOptionalSkipFlags(cfg backendconfig.Config) []error {
+	var errs []error
+	for _, flag := range []struct {
+		name  string
+		value *bool
+	}{
+		{"skip_get_ec2_platforms", cfg.SkipGetEc2Platforms},
+		{"skip_metadata_api_check", cfg.SkipMetadataApiCheck},
+		{"skip_origin_access_validation", cfg.SkipOriginAccessValidation},
+	} {
+		if flag.value != nil && !*flag.value {
+			errs = append(errs, fmt.Errorf("%s should be omitted or true", flag.name))
+		}
+	}
+	return errs
+}
+
+// AWSRequiredFields checks an AWS backend names a bucket, key, and real
+// region, and locks state through DynamoDB or a lockfile.
+func AWSRequiredFields(cfg backendconfig.Config) []error {
+	var errs []error
+	if strings.TrimSpace(cfg.Bucket) == "" {
+		errs = append(errs, errors.New("AWS backend must name a bucket"))
+	}
+	if strings.TrimSpace(cfg.Key) == "" {
+		errs = append(errs, errors.New("AWS backend must name a state key"))
+	}
+	errs = appendIf(errs, AWSRegionViolation(cfg))
+	if (cfg.DynamodbTable == nil || strings.TrimSpace(*cfg.DynamodbTable) == "") && (cfg.UseLockfile == nil || !*cfg.UseLockfile) {
+		errs = append(errs, errors.New("AWS backend must lock state with dynamodb_table or use_lockfile"))
+	}
+	return errs
+}
+
+// AWSForbiddenCredentials applies the credential rules for genuine AWS
+// backends, which may lock through DynamoDB or a lockfile.
+func AWSForbiddenCredentials(cfg backendconfig.Config) []error {
+	return appendIf(nil, InlineCredentialViolation(cfg))
+}
+
+// EncryptionKept rejects a tfbackend file that sets encrypt = false, which
+// would override the encrypt = true backend.tf requests for every estate.
+func EncryptionKept(cfg backendconfig.Config) []error {
+	if cfg.Encrypt != nil && !*cfg.Encrypt {
+		return []error{errors.New("backend config must not override encrypt = true from backend.tf")}
+	}
+	return nil
+}
+
+// ProfileOmitted is ProfileViolation as a rule list.
+func ProfileOmitted(cfg backendconfig.Config) []error {
+	return appendIf(nil, ProfileViolation(cfg))
+}
+
+// appendIf appends err to errs unless it is nil.
+func appendIf(errs []error, err error) []error {
+	if err != nil {
+		return append(errs, err)
+	}
+	return errs
+}
+
+// KeyViolation reports a state key that strays from KeyPattern.
+func KeyViolation(key string) error {
+	if !KeyPattern.MatchString(key) {
+		return fmt.Errorf("state key %q must match %s", key, KeyPattern)
+	}
+	return nil
+}
+
+// AWSRegions lists the commercial AWS regions an AWS backend may name.
+var AWSRegions = map[string]bool{
+	"af-south-1": true, "ap-east-1": true, "ap-northeast-1": true, "ap-northeast-2": true,
+	"ap-northeast-3": true, "ap-south-1": true, "ap-south-2": true, "ap-southeast-1": true,
+	"ap-southeast-2": true, "ap-southeast-3": true, "ap-southeast-4": true, "ap-southeast-5": true,
+	"ap-southeast-7": true, "ca-central-1": true, "ca-west-1": true, "eu-central-1": true,
+	"eu-central-2": true, "eu-north-1": true, "eu-south-1": true, "eu-south-2": true,
+	"eu-west-1": true, "eu-west-2": true, "eu-west-3": true, "il-central-1": true,
+	"me-central-1": true, "me-south-1": true, "mx-central-1": true, "sa-east-1": true,
+	"us-east-1": true, "us-east-2": true, "us-west-1": true, "us-west-2": true,
+}
+
+// AWSRegionViolation rejects a region that is not a real AWS region.
+func AWSRegionViolation(cfg backendconfig.Config) error {
+	if !AWSRegions[cfg.Region] {
+		return fmt.Errorf("AWS backend region %q is not a known AWS region", cfg.Region)
+	}
+	return nil
+}
+
+// ScalewayRegionEndpointViolation checks the s3 endpoint is the HTTPS
+// Object Storage host for cfg.Region, so the two cannot drift apart when an
+// estate moves region.
+func ScalewayRegionEndpointViolation(cfg backendconfig.Config) error {
+	endpoint, ok := cfg.Endpoints["s3"]
+	if !ok {
+		return fmt.Errorf("Scaleway backend must set endpoints.s3")
+	}
+	if !strings.HasPrefix(endpoint, "https://") {
+		return fmt.Errorf("Scaleway s3 endpoint %q must use https", endpoint)
+	}
+	if want := fmt.Sprintf("https://s3.%s.scw.cloud", cfg.Region); endpoint != want {
+		return fmt.Errorf("Scaleway s3 endpoint %q does not match region %q; expected %q", endpoint, cfg.Region, want)
+	}
+	return nil
+}
+
+// ScalewayEndpointViolation rejects endpoint overrides other than s3, which
+// Scaleway does not serve.
+func ScalewayEndpointViolation(cfg backendconfig.Config) error {
+	var unexpected []string
+	for name := range cfg.Endpoints {
+		if name != "s3" {
+			unexpected = append(unexpected, name)
+		}
+	}
+	if len(unexpected) > 0 {
+		sort.Strings(unexpected)
+		return fmt.Errorf("Scaleway backend endpoints must only set s3, found %s", strings.Join(unexpected, ", "))
+	}
+	return nil
+}
+
+// InlineCredentialViolation is the credential rule every backend kind
+// shares: keys and session tokens come from the environment.
+func InlineCredentialViolation(cfg backendconfig.Config) error {
+	if cfg.AccessKey != nil || cfg.SecretKey != nil {
+		return fmt.Errorf("backend config must not embed credentials")
+	}
+	if cfg.SessionToken != nil {
+		return fmt.Errorf("backend config must not embed session_token")
+	}
+	return nil
+}
+
+// ProfileViolation rejects a named AWS profile, which would tie the backend
+// to the operator's local AWS configuration.
+func ProfileViolation(cfg backendconfig.Config) error {
+	if cfg.Profile != nil {
+		return fmt.Errorf("backend config must not set profile %q; supply credentials via environment variables", *cfg.Profile)
+	}
+	return nil
+}
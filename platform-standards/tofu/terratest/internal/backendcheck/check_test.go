@@ -0,0 +1,142 @@
+package backendcheck
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendconfig"
+)
+
+func scalewayConfig(t *testing.T) backendconfig.Config {
+	t.Helper()
+
+	cfg, err := backendconfig.Scaleway(ScalewayBucket, ScalewayRegion, "foo", "main")
+	if err != nil {
+		t.Fatalf("build Scaleway config: %v", err)
+	}
+	return cfg
+}
+
+func awsConfig() backendconfig.Config {
+	table := "tfstate-locks"
+	return backendconfig.Config{
+		Bucket:        "estate-state",
+		Key:           "estates/foo/main/terraform.tfstate",
+		Region:        "eu-west-2",
+		DynamodbTable: &table,
+	}
+}
+
+// assertViolations checks errs holds one error per wanted substring, in
+// order.
+func assertViolations(t *testing.T, errs []error, want ...string) {
+	t.Helper()
+
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d violations, got %d: %v", len(want), len(errs), errs)
+	}
+	for i, fragment := range want {
+		if !strings.Contains(errs[i].Error(), fragment) {
+			t.Errorf("violation %d: expected %q in %q", i, fragment, errs[i])
+		}
+	}
+}
+
+// TestScalewayAcceptsRenderedConfig checks the settings backendgen renders
+// break no rule.
+func TestScalewayAcceptsRenderedConfig(t *testing.T) {
+	assertViolations(t, Scaleway(scalewayConfig(t)))
+}
+
+// TestScalewayReportsEveryViolation breaks one rule per group and expects
+// each to be reported rather than only the first.
+func TestScalewayReportsEveryViolation(t *testing.T) {
+	cfg := scalewayConfig(t)
+	cfg.Bucket = "other"
+	cfg.UsePathStyle = false
+	lockfile, metadata, encrypt, profile := true, false, false, "default"
+	cfg.UseLockfile = &lockfile
+	cfg.SkipMetadataApiCheck = &metadata
+	cfg.Encrypt = &encrypt
+	cfg.Profile = &profile
+
+	assertViolations(t, Scaleway(cfg),
+		`unexpected bucket "other"`,
+		"use_path_style must be true for Scaleway, got false",
+		"use_lockfile should be omitted",
+		"skip_metadata_api_check should be omitted or true",
+		"must not override encrypt = true",
+		`must not set profile "default"`,
+	)
+}
+
+// TestScalewayRequiredFieldsRejectsEndpointDrift covers the key, region,
+// and endpoint rules.
+func TestScalewayRequiredFieldsRejectsEndpointDrift(t *testing.T) {
+	cfg := scalewayConfig(t)
+	cfg.Key = "state.tfstate"
+	cfg.Region = "nl-ams"
+	cfg.Endpoints["dynamodb"] = "https://dynamodb.example"
+
+	assertViolations(t, ScalewayRequiredFields(cfg),
+		`state key "state.tfstate"`,
+		`unexpected region "nl-ams"`,
+		`expected "https://s3.nl-ams.scw.cloud"`,
+		"must only set s3, found dynamodb",
+	)
+}
+
+// TestScalewayForbiddenCredentialsRejectsInlineKeys covers embedded keys and
+// DynamoDB locking.
+func TestScalewayForbiddenCredentialsRejectsInlineKeys(t *testing.T) {
+	cfg := scalewayConfig(t)
+	key, table := "SCWXXXX", "locks"
+	cfg.AccessKey = &key
+	cfg.DynamodbTable = &table
+
+	assertViolations(t, ScalewayForbiddenCredentials(cfg),
+		"must not embed credentials",
+		"should not declare DynamoDB locking",
+	)
+}
+
+// TestEncryptionKeptAllowsOmittedOrTrue checks only an explicit
+// encrypt = false is rejected.
+func TestEncryptionKeptAllowsOmittedOrTrue(t *testing.T) {
+	cfg := scalewayConfig(t)
+	assertViolations(t, EncryptionKept(cfg))
+
+	enabled, disabled := true, false
+	cfg.Encrypt = &enabled
+	assertViolations(t, EncryptionKept(cfg))
+
+	cfg.Encrypt = &disabled
+	assertViolations(t, EncryptionKept(cfg), "must not override encrypt = true")
+}
+
+// TestAWSAcceptsLockedConfig checks a DynamoDB-locked AWS backend passes and
+// that a lockfile is an acceptable alternative.
+func TestAWSAcceptsLockedConfig(t *testing.T) {
+	assertViolations(t, AWS(awsConfig()))
+
+	cfg := awsConfig()
+	lockfile := true
+	cfg.DynamodbTable, cfg.UseLockfile = nil, &lockfile
+	assertViolations(t, AWS(cfg))
+}
+
+// TestAWSReportsEveryViolation covers the required fields, locking, and
+// credential rules.
+func TestAWSReportsEveryViolation(t *testing.T) {
+	token, encrypt := "session", false
+	cfg := backendconfig.Config{Region: ScalewayRegion, SessionToken: &token, Encrypt: &encrypt}
+
+	assertViolations(t, AWS(cfg),
+		"must name a bucket",
+		"must name a state key",
+		`region "fr-par" is not a known AWS region`,
+		"must lock state",
+		"must not embed session_token",
+		"must not override encrypt = true",
+	)
+}
@@ -0,0 +1,56 @@
+package backendcheck
+
+import (
+	"sort"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// InterpolationViolations reports every attribute in a tfbackend body whose
+// value uses ${...} or %{...} template syntax. Backend files are read as
+// literals, so "${AWS_ACCESS_KEY_ID}" never picks up the environment
+// variable it names; each error is a RangeError naming the file and
+// attribute.
+func InterpolationViolations(body *hclsyntax.Body) []error {
+	names := make([]string, 0, len(body.Attributes))
+	for name := range body.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		attr := body.Attributes[name]
+		if rng, ok := firstTemplate(attr.Expr); ok {
+			errs = append(errs, rangeErrorf(rng, "%s: %s uses interpolation syntax, but tfbackend values are not interpolated; export the setting as an environment variable instead", rng.Filename, name))
+		}
+	}
+	return errs
+}
+
+// firstTemplate finds the first template in expr that is more than a plain
+// string, including inside object and tuple values.
+func firstTemplate(expr hclsyntax.Expression) (hcl.Range, bool) {
+	var found *hcl.Range
+	hclsyntax.VisitAll(expr, func(node hclsyntax.Node) hcl.Diagnostics {
+		if found != nil {
+			return nil
+		}
+		switch tmpl := node.(type) {
+		case *hclsyntax.TemplateExpr:
+			if !tmpl.IsStringLiteral() {
+				rng := tmpl.Range()
+				found = &rng
+			}
+		case *hclsyntax.TemplateWrapExpr:
+			rng := tmpl.Range()
+			found = &rng
+		}
+		return nil
+	})
+	if found == nil {
+		return hcl.Range{}, false
+	}
+	return *found, true
+}
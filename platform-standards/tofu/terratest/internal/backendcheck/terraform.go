@@ -0,0 +1,80 @@
+package backendcheck
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// GitHubProviderVersion is the constraint backend.tf and every module pin
+// the GitHub provider to.
+const GitHubProviderVersion = "~> 6.3"
+
+// RangeError is a failed check on parsed HCL, carrying the source range it
+// should be reported against.
+type RangeError struct {
+	Range   hcl.Range
+	Message string
+}
+
+func (e *RangeError) Error() string {
+	return e.Message
+}
+
+func rangeErrorf(rng hcl.Range, format string, args ...interface{}) error {
+	return &RangeError{Range: rng, Message: fmt.Sprintf(format, args...)}
+}
+
+// TerraformBlock returns the first terraform block in body; source names the
+// file or module in the error.
+func TerraformBlock(body *hclsyntax.Body, source string) (*hclsyntax.Block, error) {
+	if blk := firstBlock(body, "terraform"); blk != nil {
+		return blk, nil
+	}
+	return nil, fmt.Errorf("expected terraform block in %s", source)
+}
+
+// RequiredProvidersBlock returns the required_providers block nested in a
+// terraform block.
+func RequiredProvidersBlock(terraformBlock *hclsyntax.Block, source string) (*hclsyntax.Block, error) {
+	if blk := firstBlock(terraformBlock.Body, "required_providers"); blk != nil {
+		return blk, nil
+	}
+	return nil, rangeErrorf(terraformBlock.DefRange(), "expected terraform.required_providers block in %s", source)
+}
+
+// GitHubProvider checks required_providers pins github to
+// GitHubProviderVersion.
+func GitHubProvider(requiredProviders *hclsyntax.Block, source string) error {
+	attr, ok := requiredProviders.Body.Attributes["github"]
+	if !ok {
+		return rangeErrorf(requiredProviders.DefRange(), "expected terraform.required_providers.github to be declared in %s", source)
+	}
+
+	value, diags := attr.Expr.Value(&hcl.EvalContext{})
+	if diags.HasErrors() {
+		return rangeErrorf(attr.SrcRange, "evaluate terraform.required_providers.github in %s: %s", source, diags.Error())
+	}
+	if !value.Type().IsObjectType() {
+		return rangeErrorf(attr.SrcRange, "expected terraform.required_providers.github in %s to be an object, got %s", source, value.Type().FriendlyName())
+	}
+
+	version, ok := value.AsValueMap()["version"]
+	if !ok {
+		return rangeErrorf(attr.SrcRange, "expected terraform.required_providers.github in %s to declare a version constraint", source)
+	}
+	if version.AsString() != GitHubProviderVersion {
+		return rangeErrorf(attr.SrcRange, "expected terraform.required_providers.github.version %q in %s, got %q", GitHubProviderVersion, source, version.AsString())
+	}
+	return nil
+}
+
+func firstBlock(body *hclsyntax.Body, blockType string) *hclsyntax.Block {
+	for _, blk := range body.Blocks {
+		if blk.Type == blockType {
+			return blk
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package backendcheck
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func parseBody(t *testing.T, src string) *hclsyntax.Body {
+	t.Helper()
+
+	file, diags := hclsyntax.ParseConfig([]byte(src), "backend.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("parse: %s", diags.Error())
+	}
+	return file.Body.(*hclsyntax.Body)
+}
+
+// requiredProviders parses src and returns its required_providers block.
+func requiredProviders(t *testing.T, src string) *hclsyntax.Block {
+	t.Helper()
+
+	terraform, err := TerraformBlock(parseBody(t, src), "backend.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	blk, err := RequiredProvidersBlock(terraform, "backend.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return blk
+}
+
+// TestTerraformBlockReportsMissingBlock checks the message and that it
+// carries no range, since there is no block to point at.
+func TestTerraformBlockReportsMissingBlock(t *testing.T) {
+	_, err := TerraformBlock(parseBody(t, `locals {}`), "backend.tf")
+	if err == nil || err.Error() != "expected terraform block in backend.tf" {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var rangeErr *RangeError
+	if errors.As(err, &rangeErr) {
+		t.Fatalf("expected a plain error, got range %v", rangeErr.Range)
+	}
+}
+
+// TestRequiredProvidersBlockPointsAtTerraformBlock checks a missing
+// required_providers block is reported against the terraform block.
+func TestRequiredProvidersBlockPointsAtTerraformBlock(t *testing.T) {
+	terraform, err := TerraformBlock(parseBody(t, "\nterraform {\n}\n"), "backend.tf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = RequiredProvidersBlock(terraform, "backend.tf")
+	var rangeErr *RangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("expected a RangeError, got %v", err)
+	}
+	if rangeErr.Message != "expected terraform.required_providers block in backend.tf" {
+		t.Fatalf("unexpected message %q", rangeErr.Message)
+	}
+	if rangeErr.Range.Filename != "backend.tf" || rangeErr.Range.Start.Line != 2 {
+		t.Fatalf("expected the error at backend.tf:2, got %v", rangeErr.Range)
+	}
+}
+
+// TestGitHubProviderRejectsMalformedPins covers each way the github entry
+// can be missing or wrong.
+func TestGitHubProviderRejectsMalformedPins(t *testing.T) {
+	cases := map[string]struct {
+		providers string
+		want      string
+	}{
+		"missing": {
+			``,
+			"expected terraform.required_providers.github to be declared in backend.tf",
+		},
+		"unevaluable": {
+			`github = { version = var.version }`,
+			"evaluate terraform.required_providers.github in backend.tf: ",
+		},
+		"not an object": {
+			`github = "~> 6.3"`,
+			"expected terraform.required_providers.github in backend.tf to be an object, got string",
+		},
+		"no version": {
+			`github = { source = "integrations/github" }`,
+			"expected terraform.required_providers.github in backend.tf to declare a version constraint",
+		},
+		"wrong version": {
+			`github = { source = "integrations/github", version = "~> 5.0" }`,
+			`expected terraform.required_providers.github.version "~> 6.3" in backend.tf, got "~> 5.0"`,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			blk := requiredProviders(t, "terraform {\n  required_providers {\n    "+tc.providers+"\n  }\n}\n")
+
+			err := GitHubProvider(blk, "backend.tf")
+			var rangeErr *RangeError
+			if !errors.As(err, &rangeErr) {
+				t.Fatalf("expected a RangeError, got %v", err)
+			}
+			// The diagnostic text after the colon belongs to HCL.
+			if strings.HasSuffix(tc.want, ": ") && strings.HasPrefix(rangeErr.Message, tc.want) {
+				return
+			}
+			if rangeErr.Message != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, rangeErr.Message)
+			}
+		})
+	}
+}
+
+// TestGitHubProviderAcceptsPinnedVersion is the positive control.
+func TestGitHubProviderAcceptsPinnedVersion(t *testing.T) {
+	blk := requiredProviders(t, `
+terraform {
+  required_providers {
+    github = { source = "integrations/github", version = "~> 6.3" }
+  }
+}
+`)
+	if err := GitHubProvider(blk, "backend.tf"); err != nil {
+		t.Fatalf("expected the pinned provider to pass: %v", err)
+	}
+}
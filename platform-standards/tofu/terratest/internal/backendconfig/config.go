@@ -0,0 +1,144 @@
+// Package backendconfig models the OpenTofu S3 backend settings Concordat
+// commits as tfbackend files, and renders new ones for operators.
+package backendconfig
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// Config decodes any S3-compatible tfbackend file. Optional settings are
+// pointers so validators can tell an omitted setting from an explicit one.
+type Config struct {
+	Bucket                     string            `hcl:"bucket"`
+	Key                        string            `hcl:"key"`
+	Region                     string            `hcl:"region"`
+	Endpoints                  map[string]string `hcl:"endpoints,optional"`
+	UsePathStyle               bool              `hcl:"use_path_style,optional"`
+	SkipRegionValidation       bool              `hcl:"skip_region_validation,optional"`
+	SkipRequestingAccountID    bool              `hcl:"skip_requesting_account_id,optional"`
+	SkipCredentialsValidation  bool              `hcl:"skip_credentials_validation,optional"`
+	UseLockfile                *bool             `hcl:"use_lockfile,optional"`
+	Encrypt                    *bool             `hcl:"encrypt,optional"`
+	AccessKey                  *string           `hcl:"access_key,optional"`
+	SecretKey                  *string           `hcl:"secret_key,optional"`
+	SessionToken               *string           `hcl:"session_token,optional"`
+	Profile                    *string           `hcl:"profile,optional"`
+	DynamodbTable              *string           `hcl:"dynamodb_table,optional"`
+	SkipGetEc2Platforms        *bool             `hcl:"skip_get_ec2_platforms,optional"`
+	SkipMetadataApiCheck       *bool             `hcl:"skip_metadata_api_check,optional"`
+	SkipOriginAccessValidation *bool             `hcl:"skip_origin_access_validation,optional"`
+}
+
+// Load decodes the tfbackend file at path.
+func Load(path string) (Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, fmt.Errorf("read backend config %s: %w", path, err)
+	}
+
+	return Decode(path, data)
+}
+
+// Decode decodes tfbackend source read from path.
+func Decode(path string, data []byte) (Config, error) {
+	var cfg Config
+	if err := hclsimple.Decode(filepath.Base(path)+".hcl", data, nil, &cfg); err != nil {
+		return Config{}, fmt.Errorf("decode backend config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// credentialAttributes are never written by Render; credentials come from
+// the environment.
+var credentialAttributes = []string{"access_key", "secret_key", "session_token", "profile"}
+
+var segmentPattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// StateKey builds the estates/<estate>/<stack>/terraform.tfstate key.
+func StateKey(estate, stack string) (string, error) {
+	for name, value := range map[string]string{"estate": estate, "stack": stack} {
+		if !segmentPattern.MatchString(value) {
+			return "", fmt.Errorf("%s %q must contain only lower-case letters, digits, and hyphens", name, value)
+		}
+	}
+	return fmt.Sprintf("estates/%s/%s/terraform.tfstate", estate, stack), nil
+}
+
+// Scaleway returns the standard Scaleway Object Storage settings for one
+// estate stack, matching backend/scaleway.tfbackend.
+func Scaleway(bucket, region, estate, stack string) (Config, error) {
+	key, err := StateKey(estate, stack)
+	if err != nil {
+		return Config{}, err
+	}
+	if strings.TrimSpace(bucket) == "" || strings.TrimSpace(region) == "" {
+		return Config{}, fmt.Errorf("bucket and region must not be empty")
+	}
+	return Config{
+		Bucket:                    bucket,
+		Key:                       key,
+		Region:                    region,
+		Endpoints:                 map[string]string{"s3": fmt.Sprintf("https://s3.%s.scw.cloud", region)},
+		UsePathStyle:              true,
+		SkipRegionValidation:      true,
+		SkipRequestingAccountID:   true,
+		SkipCredentialsValidation: true,
+	}, nil
+}
+
+// Render encodes cfg as tfbackend HCL under a header comment. Unset optional
+// settings are left out, and a config carrying credentials is refused.
+func Render(cfg Config, header string) ([]byte, error) {
+	var embedded []string
+	if cfg.AccessKey != nil {
+		embedded = append(embedded, "access_key")
+	}
+	if cfg.SecretKey != nil {
+		embedded = append(embedded, "secret_key")
+	}
+	if cfg.SessionToken != nil {
+		embedded = append(embedded, "session_token")
+	}
+	if cfg.Profile != nil {
+		embedded = append(embedded, "profile")
+	}
+	if len(embedded) > 0 {
+		sort.Strings(embedded)
+		return nil, fmt.Errorf("refusing to render credentials (%s); supply them through environment variables", strings.Join(embedded, ", "))
+	}
+
+	file := hclwrite.NewEmptyFile()
+	body := file.Body()
+	gohcl.EncodeIntoBody(&cfg, body)
+	for name, attr := range body.Attributes() {
+		if isNull(attr) {
+			body.RemoveAttribute(name)
+		}
+	}
+	for _, name := range credentialAttributes {
+		body.RemoveAttribute(name)
+	}
+
+	var out strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(header), "\n") {
+		if line != "" {
+			out.WriteString("# " + line + "\n")
+		}
+	}
+	out.Write(hclwrite.Format(file.Bytes()))
+	return []byte(out.String()), nil
+}
+
+// isNull reports whether gohcl wrote a nil optional field as null.
+func isNull(attr *hclwrite.Attribute) bool {
+	return strings.TrimSpace(string(attr.Expr().BuildTokens(nil).Bytes())) == "null"
+}
@@ -0,0 +1,220 @@
+package terratest
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// pluginCacheDir is shared by every fixture plan and backend init so the
+// GitHub provider is downloaded once per suite run. OpenTofu takes a file
+// lock on the cache while installing providers, so parallel inits can safely
+// share it; per-test TF_DATA_DIR values keep their .terraform directories
+// apart.
+var pluginCacheDir string
+
+// tofuSkipReason is set by TestMain when the OpenTofu binary cannot be found,
+// so static HCL checks still run while tofu-backed tests skip cleanly.
+var tofuSkipReason string
+
+func TestMain(m *testing.M) {
+	os.Exit(runSuite(m))
+}
+
+func runSuite(m *testing.M) int {
+	if err := resolveTofu(); err != nil {
+		if strings.TrimSpace(os.Getenv("CONCORDAT_REQUIRE_TOFU")) == "1" {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		tofuSkipReason = err.Error()
+	}
+
+	backendPath, err := fixturePath("..", "backend.tf")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	matrix, err := resolveTofuMatrix(os.Getenv("CONCORDAT_TOFU_VERSIONS"), backendPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	tofuMatrix = matrix
+
+	dir, cleanup, err := resolvePluginCacheDir(os.Getenv("TF_PLUGIN_CACHE_DIR"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer cleanup()
+
+	pluginCacheDir = dir
+	return m.Run()
+}
+
+// resolvePluginCacheDir returns the provider cache the suite shares. A
+// pre-set TF_PLUGIN_CACHE_DIR is reused and kept, so CI can persist it
+// between runs; otherwise a temporary directory is created and removed by
+// the returned cleanup.
+func resolvePluginCacheDir(preset string) (string, func(), error) {
+	if preset = strings.TrimSpace(preset); preset != "" {
+		if err := os.MkdirAll(preset, 0o755); err != nil {
+			return "", nil, fmt.Errorf("create plugin cache dir %s: %w", preset, err)
+		}
+		return preset, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "concordat-plugin-cache-")
+	if err != nil {
+		return "", nil, fmt.Errorf("create plugin cache dir: %w", err)
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
+
+// pluginCacheEnv adds the shared plugin cache to env so every options
+// helper inits against the same provider cache.
+func pluginCacheEnv(env map[string]string) map[string]string {
+	if env == nil {
+		env = map[string]string{}
+	}
+	env["TF_PLUGIN_CACHE_DIR"] = pluginCacheDir
+	return env
+}
+
+// cliArgsCommands are the tofu subcommands the suite runs; each has a
+// TF_CLI_ARGS_<command> variable that could inject flags behind its back.
+var cliArgsCommands = []string{"init", "plan", "apply", "show", "validate", "output", "workspace", "destroy"}
+
+// deterministicEnv blanks TF_CLI_ARGS and its per-command variants and forces
+// the C locale, so plan output does not depend on the developer's shell.
+func deterministicEnv(env map[string]string) map[string]string {
+	env = pluginCacheEnv(env)
+	env["TF_CLI_ARGS"] = ""
+	for _, command := range cliArgsCommands {
+		env["TF_CLI_ARGS_"+command] = ""
+	}
+	env["LC_ALL"] = "C"
+	return env
+}
+
+// resolveTofu checks that terraformBinary() names an executable, describing
+// how to fix the environment when it does not.
+func resolveTofu() error {
+	binary := terraformBinary()
+	if _, err := exec.LookPath(binary); err != nil {
+		return fmt.Errorf(
+			"OpenTofu binary %q not found; install tofu or set TERRAFORM_BINARY (CONCORDAT_REQUIRE_TOFU=1 turns this skip into a failure): %v",
+			binary, err,
+		)
+	}
+	return nil
+}
+
+// requireTofu skips the calling test when TestMain could not find OpenTofu.
+func requireTofu(t *testing.T) {
+	t.Helper()
+
+	if tofuSkipReason != "" {
+		t.Skip(tofuSkipReason)
+	}
+}
+
+// TestResolvePluginCacheDirHonoursPreset checks a pre-set cache survives
+// cleanup while a generated one is removed.
+func TestResolvePluginCacheDirHonoursPreset(t *testing.T) {
+	preset := filepath.Join(t.TempDir(), "cache")
+	dir, cleanup, err := resolvePluginCacheDir(preset)
+	if err != nil {
+		t.Fatalf("resolve preset cache: %v", err)
+	}
+	cleanup()
+	if dir != preset {
+		t.Fatalf("expected preset cache %s, got %s", preset, dir)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("expected preset cache to exist after cleanup: %v", err)
+	}
+
+	dir, cleanup, err = resolvePluginCacheDir("")
+	if err != nil {
+		t.Fatalf("resolve temporary cache: %v", err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("expected temporary cache %s to be removed, got %v", dir, err)
+	}
+}
+
+// TestBaseOptionsApplyDeterministicEnv checks every options helper starts
+// from the sanitised environment even when the caller's shell sets
+// TF_CLI_ARGS or a locale.
+func TestBaseOptionsApplyDeterministicEnv(t *testing.T) {
+	t.Setenv("TF_CLI_ARGS", "-no-color=false")
+	t.Setenv("TF_CLI_ARGS_plan", "-refresh=false")
+	t.Setenv("LC_ALL", "de_DE.UTF-8")
+
+	options := baseOptions(t, "tofu", t.TempDir())
+	if !options.NoColor {
+		t.Fatalf("expected NoColor to be set")
+	}
+	if options.PlanFilePath == "" || options.TerraformBinary != "tofu" {
+		t.Fatalf("expected a plan file and binary, got %q and %q", options.PlanFilePath, options.TerraformBinary)
+	}
+
+	want := map[string]string{
+		"TF_CLI_ARGS":         "",
+		"TF_CLI_ARGS_plan":    "",
+		"TF_CLI_ARGS_init":    "",
+		"LC_ALL":              "C",
+		"TF_PLUGIN_CACHE_DIR": pluginCacheDir,
+	}
+	for key, value := range want {
+		if got, ok := options.EnvVars[key]; !ok || got != value {
+			t.Errorf("expected %s=%q, got %q (set: %t)", key, value, got, ok)
+		}
+	}
+}
+
+// TestPlanFilePathHonoursKeepPlans checks plans land under the configured
+// directory, named by test, and stay in t.TempDir otherwise.
+func TestPlanFilePathHonoursKeepPlans(t *testing.T) {
+	t.Setenv(keepPlansEnv, "")
+	if path := planFilePath(t); !strings.HasPrefix(path, os.TempDir()) || filepath.Base(path) != "plan.tfplan" {
+		t.Fatalf("expected a temporary plan path by default, got %s", path)
+	}
+
+	root := t.TempDir()
+	t.Setenv(keepPlansEnv, root)
+	path := baseOptions(t, "tofu", t.TempDir()).PlanFilePath
+	want := filepath.Join(root, "TestPlanFilePathHonoursKeepPlans") + string(filepath.Separator)
+	if !strings.HasPrefix(path, want) {
+		t.Fatalf("expected plan file under %s, got %s", want, path)
+	}
+	if info, err := os.Stat(filepath.Dir(path)); err != nil || !info.IsDir() {
+		t.Fatalf("expected plan directory to exist: %v", err)
+	}
+	if other := planFilePath(t); other == path {
+		t.Fatalf("expected each options set to get its own plan file, both were %s", path)
+	}
+}
+
+// TestResolveTofuReportsMissingBinary points TERRAFORM_BINARY at a path that
+// cannot exist and checks the skip reason names the binary and the variables.
+func TestResolveTofuReportsMissingBinary(t *testing.T) {
+	missing := "/nonexistent/concordat/tofu"
+	t.Setenv("TERRAFORM_BINARY", missing)
+
+	err := resolveTofu()
+	if err == nil {
+		t.Fatalf("expected resolveTofu to fail for %s", missing)
+	}
+	for _, fragment := range []string{missing, "TERRAFORM_BINARY", "CONCORDAT_REQUIRE_TOFU"} {
+		if !strings.Contains(err.Error(), fragment) {
+			t.Fatalf("expected skip reason to mention %q, got %q", fragment, err)
+		}
+	}
+}
@@ -1,12 +1,24 @@
 package terratest
 
 import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
-	"io/fs"
+	"math"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -16,445 +28,3678 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/gruntwork-io/terratest/modules/terraform"
 	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/gohcl"
 	"github.com/hashicorp/hcl/v2/hclparse"
-	"github.com/hashicorp/hcl/v2/hclsimple"
 	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	tfjson "github.com/hashicorp/terraform-json"
 	"github.com/johannesboyne/gofakes3"
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendcheck"
+	"github.com/leynos/concordat/platform-standards/tofu/terratest/internal/backendconfig"
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/mod/modfile"
 )
 
-type scalewayBackendConfig struct {
-	Bucket                     string            `hcl:"bucket"`
-	Key                        string            `hcl:"key"`
-	Region                     string            `hcl:"region"`
-	Endpoints                  map[string]string `hcl:"endpoints"`
-	UsePathStyle               bool              `hcl:"use_path_style,optional"`
-	SkipRegionValidation       bool              `hcl:"skip_region_validation,optional"`
-	SkipRequestingAccountID    bool              `hcl:"skip_requesting_account_id,optional"`
-	SkipCredentialsValidation  bool              `hcl:"skip_credentials_validation,optional"`
-	UseLockfile                *bool             `hcl:"use_lockfile,optional"`
-	AccessKey                  *string           `hcl:"access_key,optional"`
-	SecretKey                  *string           `hcl:"secret_key,optional"`
-	SessionToken               *string           `hcl:"session_token,optional"`
-	DynamodbTable              *string           `hcl:"dynamodb_table,optional"`
-	SkipGetEc2Platforms        *bool             `hcl:"skip_get_ec2_platforms,optional"`
-	SkipMetadataApiCheck       *bool             `hcl:"skip_metadata_api_check,optional"`
-	SkipOriginAccessValidation *bool             `hcl:"skip_origin_access_validation,optional"`
+// scalewayBackendConfig is the shared tfbackend model; cmd/backendgen renders
+// the same struct the validators below decode into.
+type scalewayBackendConfig = backendconfig.Config
+
+// fakeS3Options customises the bucket created by startFakeS3WithOptions.
+type fakeS3Options struct {
+	// seedObjects maps object keys to bodies uploaded after bucket creation.
+	seedObjects map[string][]byte
+	// transientFailures, when set, injects 503s for its object keys once
+	// setup is complete.
+	transientFailures *transientFailures
 }
 
-// copyContext holds the source and destination directories for a stack copy operation.
-type copyContext struct {
-	src string
-	dst string
+// fakeS3StateKey is the state key the behavioural backend tests point at.
+const fakeS3StateKey = "behavioural/test/terraform.tfstate"
+
+// seededStateSnapshot is a minimal state file carrying a single output so tests
+// can tell whether tofu read the pre-existing remote state.
+var seededStateSnapshot = []byte(`{
+  "version": 4,
+  "terraform_version": "1.10.7",
+  "serial": 1,
+  "lineage": "5f0b8a57-3c3e-4c55-9d8e-6d2f1c0a7b21",
+  "outputs": {
+    "seeded_marker": {
+      "value": "from-fake-s3",
+      "type": "string"
+    }
+  },
+  "resources": [],
+  "check_results": null
 }
+`)
 
-func terraformOptions(t *testing.T, pathSegments ...string) *terraform.Options {
+// terraformOptions builds plan options for the fixture at pathSegments.
+func terraformOptions(t *testing.T, binary string, pathSegments ...string) *terraform.Options {
+	t.Helper()
+
+	return terraformOptionsWithVars(t, binary, nil, pathSegments...)
+}
+
+// terraformOptionsWithVars builds fixture options that pass vars as -var
+// flags, letting one fixture cover several input permutations.
+func terraformOptionsWithVars(t *testing.T, binary string, vars map[string]interface{}, pathSegments ...string) *terraform.Options {
+	t.Helper()
+
+	requireTofu(t)
+	options := baseOptions(t, binary, resolveFixture(t, pathSegments...))
+	options.Vars = vars
+	// Each test gets its own data directory so parallel runs against the
+	// same fixture never share a .terraform directory.
+	options.EnvVars["TF_DATA_DIR"] = filepath.Join(t.TempDir(), ".terraform")
+	return options
+}
+
+// keepPlansEnv names a directory where plan files are kept after the test
+// for post-mortem inspection; CI can upload it as an artefact.
+const keepPlansEnv = "CONCORDAT_KEEP_PLANS"
+
+// planFilePath returns where the test's plan is written: a fresh directory
+// under $CONCORDAT_KEEP_PLANS/<TestName>/ when set, otherwise t.TempDir.
+func planFilePath(t *testing.T) string {
+	t.Helper()
+
+	root := strings.TrimSpace(os.Getenv(keepPlansEnv))
+	if root == "" {
+		return filepath.Join(t.TempDir(), "plan.tfplan")
+	}
+
+	testDir := filepath.Join(root, testFileName(t))
+	if err := os.MkdirAll(testDir, 0o755); err != nil {
+		t.Fatalf("create %s directory %s: %v", keepPlansEnv, testDir, err)
+	}
+	// A test may build several option sets, so each gets its own directory.
+	dir, err := os.MkdirTemp(testDir, "plan-")
+	if err != nil {
+		t.Fatalf("create plan directory under %s: %v", testDir, err)
+	}
+	return filepath.Join(dir, "plan.tfplan")
+}
+
+// baseOptions is the starting point for every options helper: colour off, a
+// per-test plan file, and the deterministic environment, so plan output is
+// stable enough for golden comparisons.
+func baseOptions(t *testing.T, binary, dir string) *terraform.Options {
 	t.Helper()
 
-	absPath := resolveFixture(t, pathSegments...)
 	return &terraform.Options{
-		TerraformDir:    absPath,
+		TerraformDir:    dir,
 		NoColor:         true,
-		PlanFilePath:    filepath.Join(t.TempDir(), "plan.tfplan"),
-		TerraformBinary: terraformBinary(),
+		PlanFilePath:    planFilePath(t),
+		TerraformBinary: binary,
+		EnvVars:         deterministicEnv(nil),
+	}
+}
+
+// terraformOptionsTargeted builds fixture options that plan only targets and
+// their dependencies. Targeting skips the rest of the graph, so it can hide
+// dependency or validation errors in resources outside the target set; keep
+// at least one untargeted test per fixture.
+func terraformOptionsTargeted(t *testing.T, binary string, targets []string, pathSegments ...string) *terraform.Options {
+	t.Helper()
+
+	options := terraformOptions(t, binary, pathSegments...)
+	options.Targets = targets
+	return options
+}
+
+// runValidateE initialises the fixture and runs validate, which catches type
+// and syntax errors but not plan-time guardrails such as preconditions.
+func runValidateE(t *testing.T, options *terraform.Options) error {
+	t.Helper()
+
+	if _, err := terraform.InitE(t, options); err != nil {
+		return err
+	}
+	_, err := terraform.ValidateE(t, options)
+	return err
+}
+
+// planWithDiagnostics initialises the fixture and plans with -json, returning
+// the plan on success and the error and warning diagnostics either way, so
+// negative tests can check which guardrail fired.
+func planWithDiagnostics(t *testing.T, options *terraform.Options) (*terraform.PlanStruct, []string) {
+	t.Helper()
+
+	if _, err := terraform.InitE(t, options); err != nil {
+		t.Fatalf("init %s: %v", options.TerraformDir, err)
+	}
+	output, err := terraform.RunTerraformCommandE(t, options, terraform.FormatArgs(options, "plan", "-input=false", "-json")...)
+	diagnostics := parsePlanDiagnostics(output)
+	if err != nil {
+		if len(diagnostics) == 0 {
+			t.Fatalf("plan %s failed without diagnostics: %v", options.TerraformDir, err)
+		}
+		return nil, diagnostics
+	}
+
+	planStruct, err := terraform.ShowWithStructE(t, options)
+	if err != nil {
+		t.Fatalf("show plan %s: %v", options.TerraformDir, err)
+	}
+	return planStruct, diagnostics
+}
+
+// parsePlanDiagnostics extracts "summary: detail" strings from the
+// diagnostic records in `plan -json` output, ignoring any other lines.
+func parsePlanDiagnostics(output string) []string {
+	var diagnostics []string
+	for _, line := range strings.Split(output, "\n") {
+		var record struct {
+			Type       string `json:"type"`
+			Diagnostic struct {
+				Summary string `json:"summary"`
+				Detail  string `json:"detail"`
+			} `json:"diagnostic"`
+		}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(line)), &record); err != nil || record.Type != "diagnostic" {
+			continue
+		}
+		message := record.Diagnostic.Summary
+		if detail := strings.TrimSpace(record.Diagnostic.Detail); detail != "" {
+			message += ": " + detail
+		}
+		diagnostics = append(diagnostics, message)
+	}
+	return diagnostics
+}
+
+// assertDiagnosticContains fails the test unless a diagnostic contains want.
+func assertDiagnosticContains(t *testing.T, diagnostics []string, want string) {
+	t.Helper()
+
+	for _, diagnostic := range diagnostics {
+		if strings.Contains(diagnostic, want) {
+			return
+		}
+	}
+	t.Fatalf("expected a diagnostic containing %q, got %q", want, diagnostics)
+}
+
+// resolveFixture returns the absolute fixture directory, failing at once
+// with the attempted path when a segment is mistyped.
+func resolveFixture(t *testing.T, pathSegments ...string) string {
+	t.Helper()
+
+	absPath, err := resolveFixturePath(pathSegments...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return absPath
+}
+
+// resolveFixturePath does the work behind resolveFixture so its errors can
+// be checked directly.
+func resolveFixturePath(pathSegments ...string) (string, error) {
+	absPath, err := fixturePath(pathSegments...)
+	if err != nil {
+		return "", fmt.Errorf("resolve fixture %s: %v", filepath.Join(pathSegments...), err)
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", fmt.Errorf("resolve fixture %s: %v", absPath, err)
+	}
+	if !info.IsDir() {
+		return "", fmt.Errorf("resolve fixture %s: not a directory", absPath)
+	}
+	return absPath, nil
+}
+
+// repoRoot returns the directory holding this package's go.mod, located once
+// from this source file (or the working directory under -trimpath), so
+// fixture paths resolve the same way wherever the tests are run from.
+var repoRoot = sync.OnceValues(func() (string, error) {
+	start, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	if _, file, _, ok := runtime.Caller(0); ok && filepath.IsAbs(file) {
+		start = filepath.Dir(file)
+	}
+	return findModuleRoot(start)
+})
+
+// terratestModule is the module path findModuleRoot looks for, so a go.mod
+// belonging to some other module is never mistaken for ours.
+const terratestModule = "github.com/leynos/concordat/platform-standards/tofu/terratest"
+
+// findModuleRoot walks up from dir to the directory whose go.mod declares
+// terratestModule.
+func findModuleRoot(dir string) (string, error) {
+	for current := dir; ; {
+		data, err := os.ReadFile(filepath.Join(current, "go.mod"))
+		if err == nil && modfile.ModulePath(data) == terratestModule {
+			return current, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return "", fmt.Errorf("no go.mod for %s above %s", terratestModule, dir)
+		}
+		current = parent
+	}
+}
+
+// fixturePath joins pathSegments onto repoRoot, so the "..", "modules", ...
+// segments tests pass stay relative to this package rather than the working
+// directory. An absolute first segment, such as a copied stack, is used as is.
+func fixturePath(pathSegments ...string) (string, error) {
+	target := filepath.Join(pathSegments...)
+	if filepath.IsAbs(target) {
+		return target, nil
+	}
+	root, err := repoRoot()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, target), nil
+}
+
+// repoPath is fixturePath for paths that need not be directories, such as
+// backend.tf, globs, and testdata files, failing the test when the package
+// root cannot be found.
+func repoPath(t testing.TB, pathSegments ...string) string {
+	t.Helper()
+
+	path, err := fixturePath(pathSegments...)
+	if err != nil {
+		t.Fatalf("resolve %s: %v", filepath.Join(pathSegments...), err)
+	}
+	return path
+}
+
+func terraformBinary() string {
+	if binary := strings.TrimSpace(os.Getenv("TERRAFORM_BINARY")); binary != "" {
+		return binary
+	}
+	return "tofu"
+}
+
+// assertBoolTrue fails the test if the given attribute is not a true boolean.
+func assertBoolTrue(t *testing.T, attributes map[string]interface{}, key, message string) {
+	t.Helper()
+	value, ok := attributes[key].(bool)
+	if !ok || !value {
+		t.Fatalf("%s, got %#v", message, attributes[key])
+	}
+}
+
+// assertBoolFalse fails the test if the given attribute is not a false boolean.
+func assertBoolFalse(t *testing.T, attributes map[string]interface{}, key, message string) {
+	t.Helper()
+	value, ok := attributes[key].(bool)
+	if !ok || value {
+		t.Fatalf("%s, got %#v", message, attributes[key])
+	}
+}
+
+// assertStringEquals fails the test if the given attribute is not the expected string.
+func assertStringEquals(t *testing.T, attributes map[string]interface{}, key, want, message string) {
+	t.Helper()
+	value, ok := attributes[key].(string)
+	if !ok || value != want {
+		t.Fatalf("%s: want %q, got %#v", message, want, attributes[key])
+	}
+}
+
+// assertNumberEquals fails the test if the attribute is not the number want.
+// Plan JSON decodes numbers as float64 (or json.Number with UseNumber), so
+// both are accepted and compared exactly.
+func assertNumberEquals(t *testing.T, attributes map[string]interface{}, key string, want float64, message string) {
+	t.Helper()
+
+	if err := numberMismatch(attributes, key, want); err != nil {
+		t.Fatalf("%s: %v", message, err)
+	}
+}
+
+// numberMismatch reports how attributes[key] differs from want, or nil when
+// it matches exactly.
+func numberMismatch(attributes map[string]interface{}, key string, want float64) error {
+	var got float64
+	switch raw := attributes[key].(type) {
+	case float64:
+		got = raw
+	case json.Number:
+		parsed, err := raw.Float64()
+		if err != nil {
+			return fmt.Errorf("%s: want %v, got unparseable number %q", key, want, raw)
+		}
+		got = parsed
+	case nil:
+		return fmt.Errorf("%s: want %v, attribute is missing or null", key, want)
+	default:
+		return fmt.Errorf("%s: want number %v, got %T %#v", key, want, raw, raw)
+	}
+	if got != want {
+		return fmt.Errorf("%s: want %v, got %v", key, want, got)
+	}
+	return nil
+}
+
+// assertMapEntry fails the test unless attributes[key] is a map whose mapKey
+// entry is the string want.
+func assertMapEntry(t *testing.T, attributes map[string]interface{}, key, mapKey, want, message string) {
+	t.Helper()
+
+	if err := mapEntryMismatch(attributes, key, mapKey, want); err != nil {
+		t.Fatalf("%s: %v", message, err)
+	}
+}
+
+// mapEntryMismatch does the checks behind assertMapEntry. It accepts the
+// map[string]interface{} plan JSON decodes to as well as the map[string]string
+// HCL decodes to.
+func mapEntryMismatch(attributes map[string]interface{}, key, mapKey, want string) error {
+	var entry interface{}
+	var found bool
+	switch entries := attributes[key].(type) {
+	case map[string]interface{}:
+		entry, found = entries[mapKey]
+	case map[string]string:
+		entry, found = entries[mapKey]
+	case nil:
+		return fmt.Errorf("%s is not set", key)
+	default:
+		return fmt.Errorf("expected %s to be a map, got %#v", key, attributes[key])
+	}
+	if !found {
+		return fmt.Errorf("%s has no %q entry, got %#v", key, mapKey, attributes[key])
+	}
+	if value, ok := entry.(string); !ok || value != want {
+		return fmt.Errorf("%s[%q]: want %q, got %#v", key, mapKey, want, entry)
+	}
+	return nil
+}
+
+// assertIntAtLeast fails the test unless the attribute is a whole number of at
+// least min. Plan JSON decodes numbers as float64, so both forms are accepted.
+func assertIntAtLeast(t *testing.T, attributes map[string]interface{}, key string, min int, message string) {
+	t.Helper()
+
+	var value int
+	switch raw := attributes[key].(type) {
+	case float64:
+		if raw != math.Trunc(raw) {
+			t.Fatalf("%s: want a whole number, got %v", message, raw)
+		}
+		value = int(raw)
+	case int:
+		value = raw
+	case json.Number:
+		parsed, err := raw.Int64()
+		if err != nil {
+			t.Fatalf("%s: want a whole number, got %v", message, raw)
+		}
+		value = int(parsed)
+	default:
+		t.Fatalf("%s: want a number, got %#v", message, attributes[key])
+	}
+	if value < min {
+		t.Fatalf("%s: want at least %d, got %d", message, min, value)
+	}
+}
+
+// firstObject returns the sole element of a nested block attribute, which
+// plan JSON renders as a single-element list of objects.
+func firstObject(t *testing.T, attributes map[string]interface{}, key string) map[string]interface{} {
+	t.Helper()
+
+	object, err := singleListObject(attributes, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return object
+}
+
+// singleListObject does the shape checks behind firstObject so they can be
+// exercised without failing the calling test.
+func singleListObject(attributes map[string]interface{}, key string) (map[string]interface{}, error) {
+	items, ok := attributes[key].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected %s to be a list of blocks, got %#v", key, attributes[key])
+	}
+	switch len(items) {
+	case 0:
+		return nil, fmt.Errorf("expected %s to hold one block, got an empty list", key)
+	case 1:
+	default:
+		return nil, fmt.Errorf("expected %s to hold one block, got %d", key, len(items))
+	}
+	object, ok := items[0].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected %s to hold an object, got %#v", key, items[0])
+	}
+	return object, nil
+}
+
+// securityFeatureStatus digs the status of one security_and_analysis feature
+// out of a planned repository, or returns "" when the feature is absent.
+func securityFeatureStatus(t *testing.T, repository map[string]interface{}, feature string) string {
+	t.Helper()
+
+	analysis := firstObject(t, repository, "security_and_analysis")
+	if items, ok := analysis[feature].([]interface{}); !ok || len(items) == 0 {
+		return ""
+	}
+	status, _ := firstObject(t, analysis, feature)["status"].(string)
+	return status
+}
+
+// assertListContains fails the test unless the attribute is a list holding
+// the wanted string.
+func assertListContains(t *testing.T, attributes map[string]interface{}, key, want, message string) {
+	t.Helper()
+
+	items, ok := attributes[key].([]interface{})
+	if !ok {
+		t.Fatalf("%s: want a list containing %q, got %#v", message, want, attributes[key])
+	}
+	for _, item := range items {
+		if value, ok := item.(string); ok && value == want {
+			return
+		}
+	}
+	t.Fatalf("%s: want %q in %#v", message, want, items)
+}
+
+// keyedInstances returns the planned attributes of every for_each instance of
+// the resource at address, keyed by instance key.
+func keyedInstances(plan *terraform.PlanStruct, address string) map[string]map[string]interface{} {
+	instances := map[string]map[string]interface{}{}
+	for _, resource := range plan.ResourcePlannedValuesMap {
+		key, ok := resource.Index.(string)
+		if !ok || resource.Address != fmt.Sprintf("%s[%q]", address, key) {
+			continue
+		}
+		instances[key] = resource.AttributeValues
+	}
+	return instances
+}
+
+// assertResourceCount fails the test unless exactly want planned resources
+// have an address starting with prefix, such as
+// "module.team.github_team_membership.", whatever their instance keys.
+func assertResourceCount(t *testing.T, plan *terraform.PlanStruct, prefix string, want int) {
+	t.Helper()
+
+	if got := plannedResourcesWithPrefix(plan, prefix); len(got) != want {
+		t.Fatalf("expected %d planned resources under %s, got %d: %s", want, prefix, len(got), strings.Join(got, ", "))
+	}
+}
+
+// plannedResourcesWithPrefix returns the sorted planned addresses that start
+// with prefix.
+func plannedResourcesWithPrefix(plan *terraform.PlanStruct, prefix string) []string {
+	var addresses []string
+	for address := range plan.ResourcePlannedValuesMap {
+		if strings.HasPrefix(address, prefix) {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Strings(addresses)
+	return addresses
+}
+
+// assertPlannedAction fails the test unless the plan's change actions for
+// address match action, such as "create", "update", or "delete,create" for
+// a replacement.
+func assertPlannedAction(t *testing.T, plan *terraform.PlanStruct, address, action string) {
+	t.Helper()
+
+	change, ok := plan.ResourceChangesMap[address]
+	if !ok || change.Change == nil {
+		t.Fatalf("expected a planned change for %s", address)
+	}
+	actions := make([]string, len(change.Change.Actions))
+	for i, planned := range change.Change.Actions {
+		actions[i] = string(planned)
+	}
+	if got := strings.Join(actions, ","); got != action {
+		t.Fatalf("expected %s to plan %q, got %q", address, action, got)
+	}
+}
+
+// assertNoDestroys fails the test listing every address the plan would
+// delete, including replacements, since recreating a GitHub resource loses
+// its history.
+func assertNoDestroys(t *testing.T, plan *terraform.PlanStruct) {
+	t.Helper()
+
+	if destroyed := destroyedAddresses(plan); len(destroyed) > 0 {
+		t.Fatalf("expected no destroy actions, plan deletes %s", strings.Join(destroyed, ", "))
+	}
+}
+
+// destroyedAddresses returns the sorted addresses whose change actions
+// include a delete.
+func destroyedAddresses(plan *terraform.PlanStruct) []string {
+	var destroyed []string
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change == nil {
+			continue
+		}
+		for _, action := range change.Change.Actions {
+			if action == tfjson.ActionDelete {
+				destroyed = append(destroyed, address)
+				break
+			}
+		}
+	}
+	sort.Strings(destroyed)
+	return destroyed
+}
+
+// assertEmptyPlan fails the test listing every resource the plan would
+// change, for re-plans after an apply that must converge.
+func assertEmptyPlan(t *testing.T, plan *terraform.PlanStruct) {
+	t.Helper()
+
+	if pending := pendingChanges(plan); len(pending) > 0 {
+		t.Fatalf("expected an empty plan, got:\n  %s", strings.Join(pending, "\n  "))
+	}
+}
+
+// pendingChanges returns "<address>: <actions>" for every resource whose
+// actions are anything other than a lone no-op, sorted by address.
+func pendingChanges(plan *terraform.PlanStruct) []string {
+	var pending []string
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change == nil || change.Change.Actions.NoOp() {
+			continue
+		}
+		actions := make([]string, len(change.Change.Actions))
+		for i, action := range change.Change.Actions {
+			actions[i] = string(action)
+		}
+		pending = append(pending, address+": "+strings.Join(actions, ","))
+	}
+	sort.Strings(pending)
+	return pending
+}
+
+// assertStringSliceEquals fails the test unless the attribute is exactly the
+// wanted list of strings, in order.
+func assertStringSliceEquals(t *testing.T, attributes map[string]interface{}, key string, want []string, message string) {
+	t.Helper()
+
+	if err := stringSliceMismatch(attributes[key], want); err != nil {
+		t.Fatalf("%s: %s %v", message, key, err)
+	}
+}
+
+// stringSliceMismatch compares a plan list with want element-wise, naming
+// the first index that differs.
+func stringSliceMismatch(value interface{}, want []string) error {
+	items, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("want a list of strings, got %#v", value)
+	}
+	got := make([]string, len(items))
+	for i, item := range items {
+		str, ok := item.(string)
+		if !ok {
+			return fmt.Errorf("want a string at index %d, got %#v", i, item)
+		}
+		got[i] = str
+	}
+
+	for i := 0; i < len(got) && i < len(want); i++ {
+		if got[i] != want[i] {
+			return fmt.Errorf("differs at index %d: want %q, got %q (full list %q)", i, want[i], got[i], got)
+		}
+	}
+	if len(got) != len(want) {
+		return fmt.Errorf("has %d elements, want %d: want %q, got %q", len(got), len(want), want, got)
+	}
+	return nil
+}
+
+// assertStringNotEmitted fails the test if the module passed the configured
+// value through to the attribute; an omitted attribute or a provider-side
+// default both count as not emitted.
+func assertStringNotEmitted(t *testing.T, attributes map[string]interface{}, key, configured, message string) {
+	t.Helper()
+	if value, ok := attributes[key].(string); ok && value == configured {
+		t.Fatalf("%s, got %#v", message, attributes[key])
+	}
+}
+
+// assertNullInputsMatchDefaults plans a fixture that omits optional inputs and
+// one that passes them as explicit null, failing unless the normalised plans
+// are identical.
+func assertNullInputsMatchDefaults(t *testing.T, omitted, explicitNull *terraform.Options) {
+	t.Helper()
+
+	want, err := normalizePlan(tracedPlan(t, omitted))
+	if err != nil {
+		t.Fatalf("normalise plan with omitted inputs: %v", err)
+	}
+	got, err := normalizePlan(tracedPlan(t, explicitNull))
+	if err != nil {
+		t.Fatalf("normalise plan with null inputs: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("explicit null inputs should plan like omitted inputs\nwant:\n%s\ngot:\n%s", want, got)
+	}
+}
+
+// TestRepositoryModuleDefaults validates the default merge strategy logic using terraform
+// plan output so we avoid hitting the GitHub API. The fixture config parallels CI usage.
+func TestRepositoryModuleDefaults(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertBoolTrue(t, plannedRepo.AttributeValues, "allow_squash_merge", "expected squash merge to remain enabled")
+		assertBoolFalse(t, plannedRepo.AttributeValues, "allow_merge_commit", "merge commits must stay disabled")
+		assertBoolFalse(t, plannedRepo.AttributeValues, "allow_rebase_merge", "rebase merges must stay disabled")
+		assertBoolTrue(t, plannedRepo.AttributeValues, "delete_branch_on_merge", "delete_branch_on_merge should default to true")
+	})
+}
+
+// TestRepositoryModuleMatchesGolden pins every planned value of the repository
+// fixture so any change in module behaviour shows up as a golden-file diff.
+func TestRepositoryModuleMatchesGolden(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		assertPlanMatchesGolden(t, planStruct, repoPath(t, "testdata", "golden", "repository_fixture.json"))
+	})
+}
+
+// TestRepositoryModuleVisibilityInputs drives several visibility values through
+// a single fixture to prove the input reaches the planned repository intact.
+func TestRepositoryModuleVisibilityInputs(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		for _, visibility := range []string{"private", "public"} {
+			t.Run(visibility, func(t *testing.T) {
+				t.Parallel()
+
+				options := terraformOptionsWithVars(t, binary, map[string]interface{}{"visibility": visibility},
+					"..", "modules", "repository", "tests", "fixture_inputs")
+
+				planStruct := tracedPlan(t, options)
+				repoAddress := "module.repository.github_repository.this"
+				plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+				if !exists {
+					t.Fatalf("expected repository resource %s to be planned", repoAddress)
+				}
+
+				assertStringEquals(t, plannedRepo.AttributeValues, "visibility", visibility, "visibility should follow the supplied variable")
+			})
+		}
+	})
+}
+
+// TestRepositoryModuleTreatsNullInputsAsDefaults ensures callers that generate
+// HCL from JSON can pass null for optional inputs instead of omitting them.
+func TestRepositoryModuleTreatsNullInputsAsDefaults(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		omitted := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_inputs")
+		explicitNull := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_null_inputs")
+
+		assertNullInputsMatchDefaults(t, omitted, explicitNull)
+	})
+}
+
+// TestRepositoryModuleDeclaresRenameGuard fails if the name record or the
+// precondition that compares it with var.name is removed, so the behavioural
+// protection below cannot disappear silently.
+func TestRepositoryModuleDeclaresRenameGuard(t *testing.T) {
+	dir := filepath.Join("..", "modules", "repository")
+
+	var record, repository *hclsyntax.Block
+	for _, file := range parseModuleFiles(t, dir) {
+		if block := findBlock(file.body, "resource", "terraform_data", "name_record"); block != nil {
+			record = block
+		}
+		if block := findBlock(file.body, "resource", "github_repository", "this"); block != nil {
+			repository = block
+		}
+	}
+	if record == nil {
+		t.Fatalf("expected %s to declare terraform_data.name_record", dir)
+	}
+	if repository == nil {
+		t.Fatalf("expected %s to declare github_repository.this", dir)
+	}
+
+	input, ok := record.Body.Attributes["input"]
+	if !ok || !referencesVariable(input.Expr, "name") {
+		t.Fatalf("expected terraform_data.name_record.input to reference var.name")
+	}
+	recordLifecycle := findBlock(record.Body, "lifecycle")
+	if recordLifecycle == nil {
+		t.Fatalf("expected terraform_data.name_record to declare a lifecycle block")
+	}
+	ignoreChanges, ok := recordLifecycle.Body.Attributes["ignore_changes"]
+	if !ok || !listsAttribute(ignoreChanges.Expr, "input") {
+		t.Fatalf("expected terraform_data.name_record to ignore changes to input, so it keeps the applied name")
+	}
+
+	lifecycle := findBlock(repository.Body, "lifecycle")
+	if lifecycle == nil {
+		t.Fatalf("expected github_repository.this to declare a lifecycle block")
+	}
+	for _, block := range lifecycle.Body.Blocks {
+		if block.Type != "precondition" {
+			continue
+		}
+		condition, ok := block.Body.Attributes["condition"]
+		if ok && referencesVariable(condition.Expr, "allow_rename") && referencesResource(condition.Expr, "terraform_data", "name_record") {
+			return
+		}
+	}
+	t.Fatalf("expected github_repository.this to declare a precondition comparing terraform_data.name_record with var.allow_rename")
+}
+
+// listsAttribute reports whether expr is a list naming attr, as in
+// ignore_changes = [attr].
+func listsAttribute(expr hclsyntax.Expression, attr string) bool {
+	items, diags := hcl.ExprList(expr)
+	if diags.HasErrors() {
+		return false
+	}
+	for _, item := range items {
+		if hcl.ExprAsKeyword(item) == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesResource reports whether expr refers to <kind>.<name>.
+func referencesResource(expr hclsyntax.Expression, kind, name string) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != kind || len(traversal) < 2 {
+			continue
+		}
+		if attr, ok := traversal[1].(hcl.TraverseAttr); ok && attr.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// referencesVariable reports whether expr refers to var.<name>.
+func referencesVariable(expr hclsyntax.Expression, name string) bool {
+	for _, traversal := range expr.Variables() {
+		if traversal.RootName() != "var" || len(traversal) < 2 {
+			continue
+		}
+		if attr, ok := traversal[1].(hcl.TraverseAttr); ok && attr.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// TestRepositoryFixtureParameterises keeps the repository fixture's name out
+// of a string literal, so applying the fixture twice need not collide.
+func TestRepositoryFixtureParameterises(t *testing.T) {
+	files := parseModuleFiles(t, filepath.Join("..", "modules", "repository", "tests", "fixture"))
+	if err := fixtureNameViolation(files); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRepositoryFixtureNameViolationFlagsLiterals checks the guard accepts a
+// variable-sourced name and reports a literal one.
+func TestRepositoryFixtureNameViolationFlagsLiterals(t *testing.T) {
+	if err := fixtureNameViolation(parseModuleFiles(t, filepath.Join("testdata", "fixture_name", "parameterised"))); err != nil {
+		t.Fatalf("expected a variable-sourced name to pass: %v", err)
+	}
+
+	err := fixtureNameViolation(parseModuleFiles(t, filepath.Join("testdata", "fixture_name", "literal")))
+	if err == nil || !strings.Contains(err.Error(), "main.tofu:7") {
+		t.Fatalf("expected the literal name assignment to be reported, got %v", err)
+	}
+}
+
+// fixtureNameViolation reports the first module call in files whose name
+// input is not drawn from a variable or local.
+func fixtureNameViolation(files []hclFile) error {
+	for _, file := range files {
+		for _, block := range file.body.Blocks {
+			if block.Type != "module" {
+				continue
+			}
+			attr, ok := block.Body.Attributes["name"]
+			if !ok {
+				continue
+			}
+			parameterised := false
+			for _, traversal := range attr.Expr.Variables() {
+				if root := traversal.RootName(); root == "var" || root == "local" {
+					parameterised = true
+					break
+				}
+			}
+			if !parameterised {
+				return fmt.Errorf("%s: module %q sets name from a literal; source it from a variable or local", attr.SrcRange, block.Labels[0])
+			}
+		}
+	}
+	return nil
+}
+
+// TestRepositoryModuleRejectsRename applies the name record to local state
+// in a scratch copy of the module, then checks a plan that only changes the
+// name is refused unless allow_rename is set.
+func TestRepositoryModuleRejectsRename(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		moduleCopy := copyStackToTemp(t, filepath.Join("..", "modules", "repository"))
+		options := terraformOptionsWithVars(t, binary, map[string]interface{}{"name": "fixture-repo"},
+			moduleCopy, "tests", "fixture_rename")
+		// Only the record is applied so the run never reaches the GitHub API.
+		options.Targets = []string{"module.repository.terraform_data.name_record"}
+
+		if _, err := terraform.InitAndApplyE(t, options); err != nil {
+			t.Fatalf("apply name record: %v", err)
+		}
+
+		// The precondition lives on the repository, so the plans cover it.
+		options.Targets = []string{"module.repository.github_repository.this"}
+		options.Vars = map[string]interface{}{"name": "renamed-repo"}
+		planStruct, diagnostics := planWithDiagnostics(t, options)
+		if planStruct != nil {
+			t.Fatalf("expected plan to fail when the repository name changes")
+		}
+		assertDiagnosticContains(t, diagnostics, "Renaming a repository requires allow_rename = true.")
+
+		options.Vars = map[string]interface{}{"name": "renamed-repo", "allow_rename": true}
+		if _, err := terraform.PlanE(t, options); err != nil {
+			t.Fatalf("expected allow_rename to permit the rename: %v", err)
+		}
+	})
+}
+
+// TestRepositoryModuleEnablesSecretScanning ensures secret scanning and push
+// protection default on for public repositories. Private ones need a GitHub
+// Advanced Security licence, so they are only scanned once the caller opts
+// in, and GitHub rejects advanced_security on public ones.
+func TestRepositoryModuleEnablesSecretScanning(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		name                 string
+		visibility           string
+		advancedSecurity     bool
+		wantScanning         bool
+		wantAdvancedSecurity string
+	}{
+		{name: "public", visibility: "public", wantScanning: true},
+		{name: "private", visibility: "private"},
+		{name: "private_with_ghas", visibility: "private", advancedSecurity: true, wantScanning: true, wantAdvancedSecurity: "enabled"},
+	}
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				options := terraformOptionsWithVars(t, binary, map[string]interface{}{
+					"visibility":               tc.visibility,
+					"enable_advanced_security": tc.advancedSecurity,
+				}, "..", "modules", "repository", "tests", "fixture_inputs")
+
+				planStruct := tracedPlan(t, options)
+				repoAddress := "module.repository.github_repository.this"
+				plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+				if !exists {
+					t.Fatalf("expected repository resource %s to be planned", repoAddress)
+				}
+
+				if !tc.wantScanning {
+					if analysis, ok := plannedRepo.AttributeValues["security_and_analysis"].([]interface{}); ok && len(analysis) > 0 {
+						t.Fatalf("expected no security_and_analysis for a %s repository without Advanced Security, got %#v", tc.visibility, analysis)
+					}
+					return
+				}
+
+				for _, feature := range []string{"secret_scanning", "secret_scanning_push_protection"} {
+					if status := securityFeatureStatus(t, plannedRepo.AttributeValues, feature); status != "enabled" {
+						t.Fatalf("expected %s to be enabled, got %q", feature, status)
+					}
+				}
+				if status := securityFeatureStatus(t, plannedRepo.AttributeValues, "advanced_security"); status != tc.wantAdvancedSecurity {
+					t.Fatalf("expected advanced_security %q for a %s repository, got %q", tc.wantAdvancedSecurity, tc.visibility, status)
+				}
+			})
+		}
+	})
+}
+
+// TestRepositoryModuleEnablesVulnerabilityAlerts ensures Dependabot alerts
+// stay on, as they feed Concordat monitoring.
+func TestRepositoryModuleEnablesVulnerabilityAlerts(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertBoolTrue(t, plannedRepo.AttributeValues, "vulnerability_alerts", "vulnerability alerts should be enabled")
+	})
+}
+
+// TestRepositoryModuleDeleteBranchAlwaysOn keeps merged branches from piling
+// up: the default fixture deletes them, and opting out is refused.
+func TestRepositoryModuleDeleteBranchAlwaysOn(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		t.Run("default", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+			planStruct := tracedPlan(t, options)
+			plannedRepo, exists := planStruct.ResourcePlannedValuesMap["module.repository.github_repository.this"]
+			if !exists {
+				t.Fatalf("expected the repository to be planned")
+			}
+			assertBoolTrue(t, plannedRepo.AttributeValues, "delete_branch_on_merge", "delete_branch_on_merge should default to true")
+		})
+
+		t.Run("disabled", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_reject_disable_delete_branch")
+			planStruct, diagnostics := planWithDiagnostics(t, options)
+			if planStruct != nil {
+				t.Fatalf("expected disabling delete_branch_on_merge to be rejected")
+			}
+			assertDiagnosticContains(t, diagnostics, "delete_branch_on_merge is part of the Concordat baseline and cannot be disabled.")
+		})
+	})
+}
+
+// TestRepositoryModuleRequiresTopics ensures every repository is tagged, and
+// that a mandatory owner topic supplied by the caller reaches the plan.
+func TestRepositoryModuleRequiresTopics(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		ownerTopic := "owner-platform"
+		options := terraformOptionsWithVars(t, binary, map[string]interface{}{"topics": []string{ownerTopic, "fixture"}},
+			"..", "modules", "repository", "tests", "fixture_inputs")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertListContains(t, plannedRepo.AttributeValues, "topics", ownerTopic, "the owner topic should be planned")
+	})
+}
+
+// TestRootStackDefaultsRepositoryTopics plans the root stack with an
+// inventory entry that lists no topics and expects the stack default to
+// satisfy the repository module's topic requirement.
+func TestRootStackDefaultsRepositoryTopics(t *testing.T) {
+	fakeS3, bucket := startFakeS3(t)
+	defer fakeS3.Close()
+
+	dir := copyHermeticStack(t, "..")
+	inventory := `schema_version: 1
+repositories:
+  - name: test-case/untagged
+    settings:
+      description: Inventory entry without topics.
+`
+	inventoryPath := filepath.Join(dir, "inventory", "repositories.yaml")
+	if err := os.WriteFile(inventoryPath, []byte(inventory), 0o644); err != nil {
+		t.Fatalf("write inventory %s: %v", inventoryPath, err)
+	}
+
+	opts := backendInitOptions(t, dir, fakeS3BackendConfig(t, fakeS3.URL, bucket))
+	opts.PlanFilePath = planFilePath(t)
+	opts.Vars = map[string]interface{}{"github_owner": "test-case"}
+
+	planStruct := tracedPlan(t, opts)
+	repoAddress := `module.repository["test-case/untagged"].github_repository.this`
+	plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+	if !exists {
+		t.Fatalf("expected repository resource %s to be planned", repoAddress)
+	}
+
+	assertListContains(t, plannedRepo.AttributeValues, "topics", "concordat", "entries without topics should get the stack default")
+}
+
+// TestRepositoryModuleDefaultBranchIsMain ensures the standard default branch
+// name reaches the planned repository.
+func TestRepositoryModuleDefaultBranchIsMain(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_default_branch")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertStringEquals(t, plannedRepo.AttributeValues, "default_branch", "main", "default branch should be main")
+	})
+}
+
+// TestRepositoryModuleRejectsMasterBranch ensures repositories cannot be
+// configured to default to master.
+func TestRepositoryModuleRejectsMasterBranch(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptionsWithVars(t, binary, map[string]interface{}{"default_branch": "master"},
+			"..", "modules", "repository", "tests", "fixture_default_branch")
+
+		if _, err := terraform.InitAndPlanE(t, options); err == nil {
+			t.Fatalf("expected plan to fail when the default branch is master")
+		}
+	})
+}
+
+// TestRepositoryModuleAutoMergeOff ensures auto-merge stays off by default and
+// can only be enabled together with the explicit opt-in.
+func TestRepositoryModuleAutoMergeOff(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		repoAddress := "module.repository.github_repository.this"
+
+		t.Run("default", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+			planStruct := tracedPlan(t, options)
+			plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+			if !exists {
+				t.Fatalf("expected repository resource %s to be planned", repoAddress)
+			}
+
+			assertBoolFalse(t, plannedRepo.AttributeValues, "allow_auto_merge", "auto-merge should default to false")
+		})
+
+		t.Run("opted_in", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptionsWithVars(t, binary, map[string]interface{}{"auto_merge_opt_in": true},
+				"..", "modules", "repository", "tests", "fixture_auto_merge")
+			planStruct := tracedPlan(t, options)
+			plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+			if !exists {
+				t.Fatalf("expected repository resource %s to be planned", repoAddress)
+			}
+
+			assertBoolTrue(t, plannedRepo.AttributeValues, "allow_auto_merge", "auto-merge should follow the opt-in")
+		})
+
+		t.Run("not_opted_in", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_auto_merge")
+			if _, err := terraform.InitAndPlanE(t, options); err == nil {
+				t.Fatalf("expected plan to fail when auto-merge is enabled without the opt-in")
+			}
+		})
+	})
+}
+
+// TestRepositoryModuleFeatureToggles ensures the baseline enables issues and
+// disables the wiki and legacy projects, and that the wiki can be opted into.
+func TestRepositoryModuleFeatureToggles(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		repoAddress := "module.repository.github_repository.this"
+
+		t.Run("defaults", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+			planStruct := tracedPlan(t, options)
+			plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+			if !exists {
+				t.Fatalf("expected repository resource %s to be planned", repoAddress)
+			}
+
+			attrs := plannedRepo.AttributeValues
+			assertBoolTrue(t, attrs, "has_issues", "issues should be enabled by default")
+			assertBoolFalse(t, attrs, "has_wiki", "the wiki should be disabled by default")
+			assertBoolFalse(t, attrs, "has_projects", "legacy projects should be disabled by default")
+		})
+
+		t.Run("wiki override", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_wiki")
+			planStruct := tracedPlan(t, options)
+			plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+			if !exists {
+				t.Fatalf("expected repository resource %s to be planned", repoAddress)
+			}
+
+			attrs := plannedRepo.AttributeValues
+			assertBoolTrue(t, attrs, "has_wiki", "has_wiki = true should enable the wiki")
+			assertBoolTrue(t, attrs, "has_issues", "overriding the wiki should leave issues enabled")
+			assertBoolFalse(t, attrs, "has_projects", "overriding the wiki should leave projects disabled")
+		})
+	})
+}
+
+// TestRepositoryModuleArchiveRequiresConfirmation ensures archived = true
+// only plans alongside confirm_archive = true.
+func TestRepositoryModuleArchiveRequiresConfirmation(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		t.Run("unconfirmed", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_archive")
+			planStruct, diagnostics := planWithDiagnostics(t, options)
+			if planStruct != nil {
+				t.Fatalf("expected plan to fail when archived is set without confirm_archive")
+			}
+			assertDiagnosticContains(t, diagnostics, "Archiving a repository requires confirm_archive = true.")
+		})
+
+		t.Run("confirmed", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptionsWithVars(t, binary, map[string]interface{}{"confirm_archive": true},
+				"..", "modules", "repository", "tests", "fixture_archive")
+			planStruct := tracedPlan(t, options)
+			repoAddress := "module.repository.github_repository.this"
+			plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+			if !exists {
+				t.Fatalf("expected repository resource %s to be planned", repoAddress)
+			}
+			assertBoolTrue(t, plannedRepo.AttributeValues, "archived", "confirmed archival should plan archived = true")
+		})
+	})
+}
+
+// TestRepositoryModuleBlocksDirectCollaborators keeps access funnelled through
+// teams: a direct collaborator only plans with allow_direct_collaborators.
+func TestRepositoryModuleBlocksDirectCollaborators(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		t.Run("unconfirmed", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_reject_direct_collaborator")
+			planStruct, diagnostics := planWithDiagnostics(t, options)
+			if planStruct != nil {
+				t.Fatalf("expected the collaborator guard to block the plan")
+			}
+			assertDiagnosticContains(t, diagnostics, "Direct collaborators require allow_direct_collaborators = true; grant access through a team instead.")
+		})
+
+		t.Run("allowed", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_direct_collaborator")
+			planStruct := tracedPlan(t, options)
+			address := `module.repository.github_repository_collaborator.direct["octocat"]`
+			collaborator, exists := planStruct.ResourcePlannedValuesMap[address]
+			if !exists {
+				t.Fatalf("expected collaborator %s to be planned", address)
+			}
+			assertStringEquals(t, collaborator.AttributeValues, "permission", "push", "collaborator permission should follow the fixture")
+		})
+	})
+}
+
+// TestRepositoryModuleConfiguresWebhooks checks a webhook plans with JSON
+// payloads and TLS verification, and that its URL reaches the module from a
+// variable rather than a literal in either the module or the fixture.
+func TestRepositoryModuleConfiguresWebhooks(t *testing.T) {
+	t.Parallel()
+
+	var hook *hclsyntax.Block
+	for _, file := range parseModuleFiles(t, filepath.Join("..", "modules", "repository")) {
+		if hook = findBlock(file.body, "resource", "github_repository_webhook", "this"); hook != nil {
+			break
+		}
+	}
+	if hook == nil {
+		t.Fatalf("expected the repository module to declare github_repository_webhook.this")
+	}
+	configuration := findBlock(hook.Body, "configuration")
+	if configuration == nil {
+		t.Fatalf("expected github_repository_webhook.this to declare a configuration block")
+	}
+	if url, ok := configuration.Body.Attributes["url"]; !ok || len(url.Expr.Variables()) == 0 {
+		t.Fatalf("expected the webhook url to come from the webhooks input, not a literal")
+	}
+
+	fixture := parseModuleFiles(t, filepath.Join("..", "modules", "repository", "tests", "fixture_webhook"))
+	call := findBlock(fixture[0].body, "module", "repository")
+	if call == nil || call.Body.Attributes["webhooks"] == nil || !referencesVariable(call.Body.Attributes["webhooks"].Expr, "webhook_url") {
+		t.Fatalf("expected fixture_webhook to pass the webhook url in from var.webhook_url")
+	}
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_webhook")
+		planStruct := tracedPlan(t, options)
+
+		address := `module.repository.github_repository_webhook.this["ci"]`
+		webhook, exists := planStruct.ResourcePlannedValuesMap[address]
+		if !exists {
+			t.Fatalf("expected webhook %s to be planned", address)
+		}
+		assertBoolTrue(t, webhook.AttributeValues, "active", "webhooks should default to active")
+		assertListContains(t, webhook.AttributeValues, "events", "pull_request", "webhook events should follow the fixture")
+
+		assertWebhookConfiguration(t, webhook.AttributeValues, "https://hooks.example.com/concordat")
+	})
+}
+
+// assertWebhookConfiguration checks a planned webhook's configuration block
+// verifies TLS, sends JSON, and delivers to url.
+func assertWebhookConfiguration(t *testing.T, webhook map[string]interface{}, url string) {
+	t.Helper()
+
+	config := firstObject(t, webhook, "configuration")
+	assertBoolFalse(t, config, "insecure_ssl", "webhooks must verify TLS")
+	assertStringEquals(t, config, "content_type", "json", "webhooks should send JSON payloads")
+	assertStringEquals(t, config, "url", url, "webhook url should come from the webhooks input")
+}
+
+// TestRepositoryModuleRejectsMissingMergePaths ensures the validation guard blocks
+// configurations that disable every merge mode.
+func TestRepositoryModuleRejectsMissingMergePaths(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_reject_disable_merges")
+
+		planStruct, diagnostics := planWithDiagnostics(t, options)
+		if planStruct != nil {
+			t.Fatalf("expected plan to fail when all merge strategies are disabled")
+		}
+		assertDiagnosticContains(t, diagnostics, "Enable at least one supported merge strategy (squash merges are required).")
+	})
+}
+
+// TestRepositoryModuleRejectsDisallowedMergeModes ensures the guardrails block
+// attempts to re-enable merge commits or rebase merges.
+func TestRepositoryModuleRejectsDisallowedMergeModes(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_reject_enable_disallowed_merge")
+
+		planStruct, diagnostics := planWithDiagnostics(t, options)
+		if planStruct != nil {
+			t.Fatalf("expected plan to fail when merge commits or rebase merges are enabled")
+		}
+		assertDiagnosticContains(t, diagnostics, "Merge commits are disallowed by the Concordat platform standard.")
+		assertDiagnosticContains(t, diagnostics, "Rebase merges are disallowed by the Concordat platform standard.")
+	})
+}
+
+// TestRepositoryModuleOmitsMergeCommitMessages ensures configured merge commit
+// formats never reach the provider while merge commits stay disabled, which
+// keeps squash-only repositories free of provider warnings.
+func TestRepositoryModuleOmitsMergeCommitMessages(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_merge_commit_messages")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertStringNotEmitted(t, plannedRepo.AttributeValues, "merge_commit_title", "PR_TITLE", "merge_commit_title must be omitted while merge commits are disabled")
+		assertStringNotEmitted(t, plannedRepo.AttributeValues, "merge_commit_message", "PR_BODY", "merge_commit_message must be omitted while merge commits are disabled")
+	})
+}
+
+// TestRepositoryModuleSquashMessageDefaults pins squash commits to the pull
+// request title with the squashed commit messages as the body.
+func TestRepositoryModuleSquashMessageDefaults(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertStringEquals(t, plannedRepo.AttributeValues, "squash_merge_commit_title", "PR_TITLE", "squash commits should take the pull request title")
+		assertStringEquals(t, plannedRepo.AttributeValues, "squash_merge_commit_message", "COMMIT_MESSAGES", "squash commits should list the squashed commit messages")
+	})
+}
+
+// TestRepositoryModuleHonoursSquashMessageOverrides confirms callers can pick
+// another supported squash commit format.
+func TestRepositoryModuleHonoursSquashMessageOverrides(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_squash_messages")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertStringEquals(t, plannedRepo.AttributeValues, "squash_merge_commit_title", "COMMIT_OR_PR_TITLE", "squash_merge_commit_title should follow the override")
+		assertStringEquals(t, plannedRepo.AttributeValues, "squash_merge_commit_message", "PR_BODY", "squash_merge_commit_message should follow the override")
+	})
+}
+
+// TestRepositoryModuleAcceptsHomepageURL confirms a well-formed homepage URL
+// flows through to the planned repository unchanged.
+func TestRepositoryModuleAcceptsHomepageURL(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		repoAddress := "module.repository.github_repository.this"
+		plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
+		if !exists {
+			t.Fatalf("expected repository resource %s to be planned", repoAddress)
+		}
+
+		assertStringEquals(t, plannedRepo.AttributeValues, "homepage_url", "https://docs.example.com/fixture-repo", "homepage_url should pass through to the repository")
+	})
+}
+
+// TestRepositoryModuleDefersToOrgDefaults ensures use_org_defaults suppresses
+// repository-level community health files even when some are configured, so
+// the organisation's .github repository applies.
+func TestRepositoryModuleDefersToOrgDefaults(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		for _, useOrgDefaults := range []bool{true, false} {
+			t.Run(fmt.Sprintf("use_org_defaults=%t", useOrgDefaults), func(t *testing.T) {
+				t.Parallel()
+
+				options := terraformOptionsWithVars(t, binary, map[string]interface{}{"use_org_defaults": useOrgDefaults},
+					"..", "modules", "repository", "tests", "fixture_org_defaults")
+
+				planStruct := tracedPlan(t, options)
+				fileAddress := "module.repository.github_repository_file.community_health[\"CODEOWNERS\"]"
+				_, planned := planStruct.ResourcePlannedValuesMap[fileAddress]
+				if useOrgDefaults {
+					for address := range planStruct.ResourcePlannedValuesMap {
+						if strings.Contains(address, "github_repository_file.") {
+							t.Fatalf("expected no community health files with use_org_defaults, got %s", address)
+						}
+					}
+				} else if !planned {
+					t.Fatalf("expected %s to be planned without use_org_defaults", fileAddress)
+				}
+			})
+		}
+	})
+}
+
+// TestBranchModuleRequiresStatusChecks ensures strict status checks carry contexts and
+// conversation resolution is force-enabled.
+func TestBranchModuleRequiresStatusChecks(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "branch", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		protectionAddress := "module.branch.github_branch_protection.this"
+		plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
+		if !exists {
+			t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+		}
+
+		assertBoolTrue(t, plannedProtection.AttributeValues, "require_conversation_resolution", "conversation resolution guardrail should be true")
+
+		statusChecks := firstObject(t, plannedProtection.AttributeValues, "required_status_checks")
+		assertStringSliceEquals(t, statusChecks, "contexts", []string{"ci/lint", "ci/smoke", "concordat/auditor"},
+			"status check contexts should match the fixture in order")
+		assertBoolTrue(t, statusChecks, "strict", "status checks should require branches to be up to date")
+	})
+}
+
+// TestBranchModuleRequiresSignedCommits ensures protected branches reject
+// unsigned commits.
+func TestBranchModuleRequiresSignedCommits(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "branch", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		protectionAddress := "module.branch.github_branch_protection.this"
+		plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
+		if !exists {
+			t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+		}
+
+		assertBoolTrue(t, plannedProtection.AttributeValues, "require_signed_commits", "signed commits guardrail should be true")
+	})
+}
+
+// TestBranchModuleRequiresLinearHistory ensures protected branches forbid
+// merge commits, matching the repository module's squash-only guardrails.
+func TestBranchModuleRequiresLinearHistory(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "branch", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		protectionAddress := "module.branch.github_branch_protection.this"
+		plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
+		if !exists {
+			t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+		}
+
+		assertBoolTrue(t, plannedProtection.AttributeValues, "required_linear_history", "linear history guardrail should be true")
+	})
+}
+
+// TestBranchModuleEnforcesReviewCount ensures protected branches require at
+// least one approving review.
+func TestBranchModuleEnforcesReviewCount(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "branch", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		protectionAddress := "module.branch.github_branch_protection.this"
+		plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
+		if !exists {
+			t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+		}
+
+		review := firstObject(t, plannedProtection.AttributeValues, "required_pull_request_reviews")
+		assertIntAtLeast(t, review, "required_approving_review_count", 1, "protected branches need an approving review")
+	})
+}
+
+// TestBranchModuleDismissesStaleReviews ensures the default review policy
+// dismisses stale approvals and requires code owner review.
+func TestBranchModuleDismissesStaleReviews(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "branch", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		protectionAddress := "module.branch.github_branch_protection.this"
+		plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
+		if !exists {
+			t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+		}
+
+		review := firstObject(t, plannedProtection.AttributeValues, "required_pull_request_reviews")
+		assertBoolTrue(t, review, "dismiss_stale_reviews", "stale reviews should be dismissed on new pushes")
+		assertBoolTrue(t, review, "require_code_owner_reviews", "code owner review should be required")
+	})
+}
+
+// TestBranchModuleEnforcesAdmins ensures admins cannot bypass branch
+// protection.
+func TestBranchModuleEnforcesAdmins(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "branch", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		protectionAddress := "module.branch.github_branch_protection.this"
+		plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
+		if !exists {
+			t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+		}
+
+		assertBoolTrue(t, plannedProtection.AttributeValues, "enforce_admins", "enforce_admins guardrail should be true")
+	})
+}
+
+// TestBranchModuleAllowsReviewOnlyGate ensures null status checks drop the
+// status check gate without touching review or conversation requirements.
+func TestBranchModuleAllowsReviewOnlyGate(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "branch", "tests", "fixture_review_only")
+
+		planStruct := tracedPlan(t, options)
+		protectionAddress := "module.branch.github_branch_protection.this"
+		plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
+		if !exists {
+			t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+		}
+
+		assertBoolTrue(t, plannedProtection.AttributeValues, "require_conversation_resolution", "conversation resolution guardrail should be true")
+
+		firstObject(t, plannedProtection.AttributeValues, "required_pull_request_reviews")
+		if statusChecks, ok := plannedProtection.AttributeValues["required_status_checks"].([]interface{}); ok && len(statusChecks) > 0 {
+			t.Fatalf("expected no required status checks for a review-only gate, got %#v", statusChecks)
+		}
+	})
+}
+
+// TestTeamModulePermissionMap verifies the module honours explicit repository permissions
+// and deduplicates maintainers when declared more than once.
+func TestTeamModulePermissionMap(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "team", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		maintainerKey := "module.team.github_team_membership.maintainers[\"alice\"]"
+		if _, exists := planStruct.ResourcePlannedValuesMap[maintainerKey]; !exists {
+			t.Fatalf("expected maintainer membership %s to be planned", maintainerKey)
+		}
+
+		memberKey := "module.team.github_team_membership.members[\"bob\"]"
+		if _, exists := planStruct.ResourcePlannedValuesMap[memberKey]; !exists {
+			t.Fatalf("expected member mapping %s to be planned", memberKey)
+		}
+
+		repoPermissionsAddress := "module.team.github_team_repository.default_permissions[\"fixture-repo\"]"
+		if _, exists := planStruct.ResourcePlannedValuesMap[repoPermissionsAddress]; !exists {
+			t.Fatalf("expected repository permission mapping %s to be created", repoPermissionsAddress)
+		}
+
+		assertResourceCount(t, planStruct, "module.team.github_team_membership.", 2)
+
+		roles := map[string]string{
+			"module.team.github_team_membership.maintainers": "maintainer",
+			"module.team.github_team_membership.members":     "member",
+		}
+		for address, role := range roles {
+			instances := keyedInstances(planStruct, address)
+			if len(instances) == 0 {
+				t.Fatalf("expected %s to plan at least one instance", address)
+			}
+			for username, attributes := range instances {
+				assertStringEquals(t, attributes, "role", role, fmt.Sprintf("%s[%q] should hold the %s role", address, username, role))
+			}
+		}
+	})
+}
+
+// TestKeyedInstancesSelectsForEachInstances checks instances of other
+// resources sharing the address prefix are left out.
+func TestKeyedInstancesSelectsForEachInstances(t *testing.T) {
+	resource := func(address string, index interface{}, role string) *tfjson.StateResource {
+		return &tfjson.StateResource{Address: address, Index: index, AttributeValues: map[string]interface{}{"role": role}}
+	}
+	plan := &terraform.PlanStruct{ResourcePlannedValuesMap: map[string]*tfjson.StateResource{
+		`team.members["bob"]`:     resource(`team.members["bob"]`, "bob", "member"),
+		`team.members["carol"]`:   resource(`team.members["carol"]`, "carol", "member"),
+		`team.members_extra["x"]`: resource(`team.members_extra["x"]`, "x", "member"),
+		`team.members[0]`:         resource(`team.members[0]`, float64(0), "member"),
+	}}
+
+	got := keyedInstances(plan, "team.members")
+	if len(got) != 2 || got["bob"] == nil || got["carol"] == nil {
+		t.Fatalf("expected the bob and carol instances, got %v", got)
+	}
+}
+
+// TestPlannedResourcesWithPrefixCountsInstances counts every for_each
+// instance under a type prefix and ignores types that merely share a stem.
+func TestPlannedResourcesWithPrefixCountsInstances(t *testing.T) {
+	plan := &terraform.PlanStruct{ResourcePlannedValuesMap: map[string]*tfjson.StateResource{
+		`module.team.github_team.this`:                            {},
+		`module.team.github_team_membership.maintainers["alice"]`: {},
+		`module.team.github_team_membership.members["bob"]`:       {},
+		`module.team.github_team_membership.members["carol"]`:     {},
+		`module.other.github_team_membership.members["dave"]`:     {},
+	}}
+
+	got := plannedResourcesWithPrefix(plan, "module.team.github_team_membership.")
+	want := []string{
+		`module.team.github_team_membership.maintainers["alice"]`,
+		`module.team.github_team_membership.members["bob"]`,
+		`module.team.github_team_membership.members["carol"]`,
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected %q, got %q", want, got)
+	}
+	if got := plannedResourcesWithPrefix(plan, "module.team.github_team_membership.members["); len(got) != 2 {
+		t.Fatalf("expected the members prefix to match two instances, got %q", got)
+	}
+}
+
+// TestTeamModuleTargetedPlan ensures a targeted plan covers only the
+// requested memberships and what they depend on.
+func TestTeamModuleTargetedPlan(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptionsTargeted(t, binary, []string{"module.team.github_team_membership.members"},
+			"..", "modules", "team", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		memberKey := "module.team.github_team_membership.members[\"bob\"]"
+		if _, exists := planStruct.ResourcePlannedValuesMap[memberKey]; !exists {
+			t.Fatalf("expected targeted member mapping %s to be planned", memberKey)
+		}
+		if _, exists := planStruct.ResourcePlannedValuesMap["module.team.github_team.this"]; !exists {
+			t.Fatalf("expected the team the membership depends on to be planned")
+		}
+
+		repoPermissionsAddress := "module.team.github_team_repository.default_permissions[\"fixture-repo\"]"
+		if _, exists := planStruct.ResourcePlannedValuesMap[repoPermissionsAddress]; exists {
+			t.Fatalf("expected untargeted %s to be left out of the plan", repoPermissionsAddress)
+		}
+	})
+}
+
+// TestTeamModuleSupportsParentTeam ensures a supplied parent team ID nests the
+// team, and that omitting it leaves a top-level team.
+func TestTeamModuleSupportsParentTeam(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		teamAddress := "module.team.github_team.this"
+
+		t.Run("nested", func(t *testing.T) {
+			t.Parallel()
+
+			parentTeamID := "4567890"
+			options := terraformOptionsWithVars(t, binary, map[string]interface{}{"parent_team_id": parentTeamID},
+				"..", "modules", "team", "tests", "fixture_parent_team")
+			planStruct := tracedPlan(t, options)
+			plannedTeam, exists := planStruct.ResourcePlannedValuesMap[teamAddress]
+			if !exists {
+				t.Fatalf("expected team resource %s to be planned", teamAddress)
+			}
+
+			assertStringEquals(t, plannedTeam.AttributeValues, "parent_team_id", parentTeamID, "parent_team_id should reference the parent team")
+		})
+
+		t.Run("top_level", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "team", "tests", "fixture_parent_team")
+			planStruct := tracedPlan(t, options)
+			plannedTeam, exists := planStruct.ResourcePlannedValuesMap[teamAddress]
+			if !exists {
+				t.Fatalf("expected team resource %s to be planned", teamAddress)
+			}
+
+			if parent := plannedTeam.AttributeValues["parent_team_id"]; parent != nil {
+				t.Fatalf("expected parent_team_id to be null for a top-level team, got %#v", parent)
+			}
+		})
+	})
+}
+
+// TestTeamModuleAcceptsMaintainPermission ensures the highest permitted grant
+// still plans.
+func TestTeamModuleAcceptsMaintainPermission(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "team", "tests", "fixture_team_maintain")
+
+		planStruct := tracedPlan(t, options)
+		permissionAddress := "module.team.github_team_repository.default_permissions[\"fixture-repo\"]"
+		plannedPermission, exists := planStruct.ResourcePlannedValuesMap[permissionAddress]
+		if !exists {
+			t.Fatalf("expected repository permission mapping %s to be planned", permissionAddress)
+		}
+
+		assertStringEquals(t, plannedPermission.AttributeValues, "permission", "maintain", "maintain should be accepted")
+	})
+}
+
+// TestTeamModuleEnforcesClosedPrivacy ensures teams stay visible to the
+// Auditor.
+func TestTeamModuleEnforcesClosedPrivacy(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "team", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		teamAddress := "module.team.github_team.this"
+		plannedTeam, exists := planStruct.ResourcePlannedValuesMap[teamAddress]
+		if !exists {
+			t.Fatalf("expected team resource %s to be planned", teamAddress)
+		}
+
+		assertStringEquals(t, plannedTeam.AttributeValues, "privacy", "closed", "teams should be closed")
+	})
+}
+
+// TestTeamModuleValidateSeparatesTypeErrors ensures type errors surface at
+// validate while logic guardrails only fire at plan. The team module guards
+// its inputs with variable validations alone, so the plan-only guardrail is
+// the repository module's direct-collaborator precondition.
+func TestTeamModuleValidateSeparatesTypeErrors(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		t.Run("type error", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "team", "tests", "fixture_reject_type_error")
+			if err := runValidateE(t, options); err == nil {
+				t.Fatalf("expected validate to reject a mistyped maintainers input")
+			}
+		})
+
+		t.Run("guardrail", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "repository", "tests", "fixture_reject_direct_collaborator")
+			if err := runValidateE(t, options); err != nil {
+				t.Fatalf("expected a direct collaborator to pass validate: %v", err)
+			}
+			if _, err := terraform.PlanE(t, options); err == nil {
+				t.Fatalf("expected plan to reject a direct collaborator")
+			}
+		})
+	})
+}
+
+// TestRulesetModuleDefaults ensures the ruleset is enforced and carries the
+// guardrails classic branch protection provides.
+func TestRulesetModuleDefaults(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "ruleset", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		rulesetAddress := "module.ruleset.github_repository_ruleset.this"
+		plannedRuleset, exists := planStruct.ResourcePlannedValuesMap[rulesetAddress]
+		if !exists {
+			t.Fatalf("expected ruleset resource %s to be planned", rulesetAddress)
+		}
+
+		attrs := plannedRuleset.AttributeValues
+		assertStringEquals(t, attrs, "enforcement", "active", "ruleset should be actively enforced")
+		assertStringEquals(t, attrs, "target", "branch", "ruleset should target branches")
+
+		rules := firstObject(t, attrs, "rules")
+		configured := map[string]interface{}{"rules": configuredRules(rules)}
+		assertListContains(t, configured, "rules", "required_status_checks", "ruleset should require status checks")
+		assertBoolTrue(t, rules, "required_signatures", "ruleset should require signed commits")
+		assertBoolTrue(t, rules, "non_fast_forward", "ruleset should block force pushes")
+
+		statusChecks := firstObject(t, rules, "required_status_checks")
+		assertBoolTrue(t, statusChecks, "strict_required_status_checks_policy", "status checks should be strict by default")
+		check := firstObject(t, statusChecks, "required_check")
+		assertStringEquals(t, check, "context", "concordat/auditor", "ruleset should require the Auditor check")
+		assertNumberEquals(t, check, "integration_id", 15368, "status check should be pinned to the fixture app")
+	})
+}
+
+// TestEnvironmentModuleDefaults ensures a production environment waits for a
+// reviewer team and only deploys from protected branches.
+func TestEnvironmentModuleDefaults(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "environment", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		environmentAddress := "module.environment.github_repository_environment.this"
+		plannedEnvironment, exists := planStruct.ResourcePlannedValuesMap[environmentAddress]
+		if !exists {
+			t.Fatalf("expected environment resource %s to be planned", environmentAddress)
+		}
+
+		attrs := plannedEnvironment.AttributeValues
+		assertStringEquals(t, attrs, "environment", "production", "fixture should plan the production environment")
+		assertNumberEquals(t, attrs, "wait_timer", 10, "wait_timer should follow the fixture")
+		assertBoolFalse(t, attrs, "can_admins_bypass", "admins should not bypass environment reviews")
+
+		reviewers := firstObject(t, attrs, "reviewers")
+		teams, ok := reviewers["teams"].([]interface{})
+		if !ok || len(teams) != 1 || teams[0] != float64(4242) {
+			t.Fatalf("expected reviewer team 4242 to be wired, got %#v", reviewers["teams"])
+		}
+
+		branchPolicy := firstObject(t, attrs, "deployment_branch_policy")
+		assertBoolTrue(t, branchPolicy, "protected_branches", "deployments should be restricted to protected branches")
+		assertBoolFalse(t, branchPolicy, "custom_branch_policies", "custom branch policies should stay disabled")
+	})
+}
+
+// TestActionsPermissionsRestrictsToSelected ensures workflows may only use
+// allow-listed actions.
+func TestActionsPermissionsRestrictsToSelected(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "actions_permissions", "tests", "fixture")
+
+		planStruct := tracedPlan(t, options)
+		permissionsAddress := "module.actions_permissions.github_actions_repository_permissions.this"
+		plannedPermissions, exists := planStruct.ResourcePlannedValuesMap[permissionsAddress]
+		if !exists {
+			t.Fatalf("expected Actions permissions resource %s to be planned", permissionsAddress)
+		}
+
+		attrs := plannedPermissions.AttributeValues
+		assertStringEquals(t, attrs, "allowed_actions", "selected", "Actions should be restricted to selected actions")
+		assertStringNotEmitted(t, attrs, "allowed_actions", "all", "Actions must not allow every action")
+
+		config := firstObject(t, attrs, "allowed_actions_config")
+		assertBoolTrue(t, config, "github_owned_allowed", "GitHub-owned actions should be allowed")
+		assertListContains(t, config, "patterns_allowed", "leynos/concordat/*", "fixture pattern should be allow-listed")
+	})
+}
+
+// configuredRules lists the rules a planned ruleset actually sets, skipping
+// attributes left null or false and blocks left empty.
+func configuredRules(rules map[string]interface{}) []interface{} {
+	var names []string
+	for name, value := range rules {
+		switch v := value.(type) {
+		case nil:
+			continue
+		case bool:
+			if !v {
+				continue
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	configured := make([]interface{}, len(names))
+	for i, name := range names {
+		configured[i] = name
+	}
+	return configured
+}
+
+// TestAssertPlannedActionDistinguishesCreateAndUpdate checks the helper
+// tells a fresh resource from an in-place change to existing state.
+func TestAssertPlannedActionDistinguishesCreateAndUpdate(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		t.Run("create", func(t *testing.T) {
+			t.Parallel()
+
+			options := terraformOptions(t, binary, "..", "modules", "team", "tests", "fixture")
+			planStruct := tracedPlan(t, options)
+			assertPlannedAction(t, planStruct, "module.team.github_team.this", "create")
+		})
+
+		t.Run("update", func(t *testing.T) {
+			t.Parallel()
+
+			stack := copyStackToTemp(t, filepath.Join("testdata", "planned_action"))
+			options := terraformOptionsWithVars(t, binary, map[string]interface{}{"value": "seeded"}, stack)
+			terraform.InitAndApply(t, options)
+
+			options.Vars = map[string]interface{}{"value": "changed"}
+			planStruct := tracedPlan(t, options)
+			assertPlannedAction(t, planStruct, "terraform_data.this", "update")
+		})
+	})
+}
+
+// TestModuleResourcesFollowNamingConvention keeps resource addresses predictable
+// by requiring every single-instance GitHub resource to be named "this".
+// Resources expanded with for_each or count may use descriptive names.
+func TestModuleResourcesFollowNamingConvention(t *testing.T) {
+	for _, dir := range moduleDirs(t) {
+		for _, file := range parseModuleFiles(t, dir) {
+			for _, block := range file.body.Blocks {
+				if !isPrimaryResource(block) || isMultiInstance(block) {
+					continue
+				}
+				if block.Labels[1] != "this" {
+					t.Errorf("%s: single-instance resource %s.%s should be named \"this\"", file.path, block.Labels[0], block.Labels[1])
+				}
+			}
+		}
+	}
+}
+
+// TestAllVariablesDocumented requires every module variable to declare a
+// type and a description.
+func TestAllVariablesDocumented(t *testing.T) {
+	for _, dir := range moduleDirs(t) {
+		for _, problem := range undocumentedVariables(parseModuleFiles(t, dir)) {
+			t.Errorf("module %s: %s", filepath.Base(dir), problem)
+		}
+	}
+}
+
+// TestUndocumentedVariablesFlagsMissingAttributes checks the specimen's
+// undocumented variables are reported and the documented one is not.
+func TestUndocumentedVariablesFlagsMissingAttributes(t *testing.T) {
+	got := undocumentedVariables(parseModuleFiles(t, filepath.Join("testdata", "undocumented_variables")))
+	want := []string{
+		`variable "missing_description" has no description`,
+		`variable "missing_type" has no type`,
+		`variable "bare" has no type`,
+		`variable "bare" has no description`,
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d problems, got %q", len(want), got)
+	}
+	for i := range want {
+		if !strings.HasSuffix(got[i], want[i]) {
+			t.Errorf("problem %d: expected %q, got %q", i, want[i], got[i])
+		}
+	}
+}
+
+// undocumentedVariables reports each variable block in files that lacks a
+// type or description attribute, prefixed with its file and line.
+func undocumentedVariables(files []hclFile) []string {
+	var problems []string
+	for _, file := range files {
+		for _, block := range file.body.Blocks {
+			if block.Type != "variable" || len(block.Labels) != 1 {
+				continue
+			}
+			for _, required := range []string{"type", "description"} {
+				if _, ok := block.Body.Attributes[required]; !ok {
+					problems = append(problems, fmt.Sprintf("%s:%d: variable %q has no %s", file.path, block.DefRange().Start.Line, block.Labels[0], required))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// TestRepositoryModuleExposesOutputs guards the outputs downstream stacks
+// read from the repository module.
+func TestRepositoryModuleExposesOutputs(t *testing.T) {
+	assertModuleOutputs(t, "repository", "repository_id", "repository_name", "repository_node_id", "full_name", "html_url")
+}
+
+// TestBranchModuleExposesOutputs guards the branch module's public outputs.
+func TestBranchModuleExposesOutputs(t *testing.T) {
+	assertModuleOutputs(t, "branch", "branch_pattern", "required_approvals")
+}
+
+// TestTeamModuleExposesOutputs guards the team module's public outputs.
+func TestTeamModuleExposesOutputs(t *testing.T) {
+	assertModuleOutputs(t, "team", "team_id", "team_slug")
+}
+
+// assertModuleOutputs fails for each name that no output block in the
+// module's top-level files declares.
+func assertModuleOutputs(t *testing.T, module string, names ...string) {
+	t.Helper()
+
+	files := parseModuleFiles(t, filepath.Join("..", "modules", module))
+	for _, name := range names {
+		found := false
+		for _, file := range files {
+			if findBlock(file.body, "output", name) != nil {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("module %s: missing output %q", module, name)
+		}
+	}
+}
+
+// hclFile pairs a parsed configuration body with the path it was read from so
+// failures can name the offending file.
+type hclFile struct {
+	path string
+	body *hclsyntax.Body
+}
+
+// moduleDirs lists every module directory under modules/ in sorted order.
+func moduleDirs(t *testing.T) []string {
+	t.Helper()
+
+	modules := repoPath(t, "..", "modules")
+	entries, err := os.ReadDir(modules)
+	if err != nil {
+		t.Fatalf("list modules: %v", err)
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			dirs = append(dirs, filepath.Join(modules, entry.Name()))
+		}
+	}
+	if len(dirs) == 0 {
+		t.Fatalf("expected at least one module under modules/")
+	}
+	return dirs
+}
+
+// parseModuleFiles parses the top-level .tofu and .tf files of a module,
+// ignoring nested test fixtures.
+func parseModuleFiles(t *testing.T, dir string) []hclFile {
+	t.Helper()
+
+	dir = repoPath(t, dir)
+	var paths []string
+	for _, pattern := range []string{"*.tofu", "*.tf"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			t.Fatalf("glob %s in %s: %v", pattern, dir, err)
+		}
+		paths = append(paths, matches...)
+	}
+	sort.Strings(paths)
+
+	parser := hclparse.NewParser()
+	files := make([]hclFile, 0, len(paths))
+	for _, path := range paths {
+		file, diag := parser.ParseHCLFile(path)
+		if diag.HasErrors() {
+			t.Fatalf("parse %s: %s", path, diag.Error())
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			t.Fatalf("%s unexpected body type %T", path, file.Body)
+		}
+		files = append(files, hclFile{path: path, body: body})
+	}
+	return files
+}
+
+// isPrimaryResource reports whether block declares a GitHub provider resource,
+// leaving auxiliary resources such as terraform_data out of naming checks.
+func isPrimaryResource(block *hclsyntax.Block) bool {
+	return block.Type == "resource" && len(block.Labels) == 2 && strings.HasPrefix(block.Labels[0], "github_")
+}
+
+func isMultiInstance(block *hclsyntax.Block) bool {
+	_, hasForEach := block.Body.Attributes["for_each"]
+	_, hasCount := block.Body.Attributes["count"]
+	return hasForEach || hasCount
+}
+
+// TestCompositeStackDestroyOrder plans a stack composing the repository,
+// branch, and team modules and checks its module dependency graph is acyclic,
+// so tofu can tear the stack down in reverse dependency order. This is the
+// plan-only half; TestCompositeStackAppliesAndDestroys runs the teardown.
+func TestCompositeStackDestroyOrder(t *testing.T) {
+	t.Parallel()
+
+	compositeDir := repoPath(t, "testdata", "composite")
+	parser := hclparse.NewParser()
+	file, diag := parser.ParseHCLFile(filepath.Join(compositeDir, "main.tofu"))
+	if diag.HasErrors() {
+		t.Fatalf("parse composite stack: %s", diag.Error())
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		t.Fatalf("composite stack unexpected body type %T", file.Body)
+	}
+
+	graph := moduleDependencyGraph(body)
+	order, err := destroyOrder(graph)
+	if err != nil {
+		t.Fatalf("composite stack cannot be destroyed cleanly: %v", err)
+	}
+	assertDestroyOrderRespectsDependencies(t, graph, order)
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, compositeDir)
+		if _, err := terraform.InitAndPlanE(t, options); err != nil {
+			t.Fatalf("expected composite stack to plan cleanly: %v", err)
+		}
+	})
+}
+
+// TestDestroyOrderRejectsCycles ensures the graph analysis reports module
+// cycles rather than producing a partial order.
+func TestDestroyOrderRejectsCycles(t *testing.T) {
+	graph := map[string][]string{
+		"repository": {"team"},
+		"branch":     {"repository"},
+		"team":       {"branch"},
+	}
+
+	if _, err := destroyOrder(graph); err == nil {
+		t.Fatalf("expected a dependency cycle to be reported")
+	}
+}
+
+// TestParsePlanDiagnosticsMatchesGuardrailText checks diagnostics are
+// pulled from plan -json records so a custom message can be matched.
+func TestParsePlanDiagnosticsMatchesGuardrailText(t *testing.T) {
+	output := `{"@level":"info","@message":"OpenTofu 1.10.7","type":"version"}
+{"@level":"error","type":"diagnostic","diagnostic":{"severity":"error","summary":"Resource precondition failed","detail":"Merge commits are disallowed by the Concordat platform standard."}}
+not json
+{"@level":"warn","type":"diagnostic","diagnostic":{"severity":"warning","summary":"Deprecated attribute","detail":""}}
+`
+
+	diagnostics := parsePlanDiagnostics(output)
+	want := []string{
+		"Resource precondition failed: Merge commits are disallowed by the Concordat platform standard.",
+		"Deprecated attribute",
+	}
+	if !reflect.DeepEqual(diagnostics, want) {
+		t.Fatalf("expected diagnostics %q, got %q", want, diagnostics)
+	}
+	assertDiagnosticContains(t, diagnostics, "Merge commits are disallowed")
+}
+
+// TestStringSliceMismatchReportsDifferences covers matching lists, order and
+// length mismatches, and non-string elements.
+func TestStringSliceMismatchReportsDifferences(t *testing.T) {
+	want := []string{"ci/lint", "ci/smoke"}
+	cases := []struct {
+		name    string
+		value   interface{}
+		wantErr string
+	}{
+		{name: "equal", value: []interface{}{"ci/lint", "ci/smoke"}},
+		{name: "order", value: []interface{}{"ci/smoke", "ci/lint"}, wantErr: "index 0"},
+		{name: "shorter", value: []interface{}{"ci/lint"}, wantErr: "has 1 elements, want 2"},
+		{name: "longer", value: []interface{}{"ci/lint", "ci/smoke", "extra"}, wantErr: "has 3 elements, want 2"},
+		{name: "non-string", value: []interface{}{"ci/lint", 2.0}, wantErr: "string at index 1"},
+		{name: "not a list", value: "ci/lint", wantErr: "list of strings"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := stringSliceMismatch(tc.value, want)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected lists to match, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestResolveFixturePathRejectsMissingAndFilePaths checks mistyped segments
+// and files fail with the attempted absolute path.
+func TestResolveFixturePathRejectsMissingAndFilePaths(t *testing.T) {
+	if _, err := resolveFixturePath("..", "modules", "team", "tests", "fixture"); err != nil {
+		t.Fatalf("expected the team fixture to resolve: %v", err)
+	}
+
+	missing, err := filepath.Abs(filepath.Join("..", "modules", "team", "tests", "fixtrue"))
+	if err != nil {
+		t.Fatalf("resolve expected path: %v", err)
+	}
+	if _, err := resolveFixturePath("..", "modules", "team", "tests", "fixtrue"); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Fatalf("expected an error naming %s, got %v", missing, err)
+	}
+
+	file, err := filepath.Abs(filepath.Join("..", "modules", "team", "main.tofu"))
+	if err != nil {
+		t.Fatalf("resolve expected path: %v", err)
+	}
+	if _, err := resolveFixturePath("..", "modules", "team", "main.tofu"); err == nil || !strings.Contains(err.Error(), file+": not a directory") {
+		t.Fatalf("expected a not-a-directory error naming %s, got %v", file, err)
+	}
+}
+
+// TestFixturePathIgnoresWorkingDirectory resolves a fixture from a nested
+// working directory and expects the same path as from the package root, then
+// runs static checks from there to prove their helpers resolve the same way.
+func TestFixturePathIgnoresWorkingDirectory(t *testing.T) {
+	want, err := filepath.Abs(filepath.Join("..", "modules", "team", "tests", "fixture"))
+	if err != nil {
+		t.Fatalf("resolve expected path: %v", err)
+	}
+
+	t.Chdir(filepath.Join("testdata", "backend"))
+	got, err := resolveFixturePath("..", "modules", "team", "tests", "fixture")
+	if err != nil {
+		t.Fatalf("resolve fixture from testdata/backend: %v", err)
+	}
+	if got != want {
+		t.Fatalf("expected %s, got %s", want, got)
+	}
+
+	if err := backendEncryptionViolation(loadBackendS3Block(t, filepath.Join("..", "backend.tf"))); err != nil {
+		t.Fatalf("backend.tf from testdata/backend: %v", err)
+	}
+	for _, dir := range moduleDirs(t) {
+		if missing := undocumentedVariables(parseModuleFiles(t, dir)); len(missing) > 0 {
+			t.Fatalf("%s from testdata/backend: undocumented variables %v", dir, missing)
+		}
+	}
+
+	if root, err := findModuleRoot(t.TempDir()); err == nil {
+		t.Fatalf("expected no terratest go.mod above a temp dir, found %s", root)
+	}
+}
+
+// TestMapEntryMismatchReportsEachFailure covers a match in both map shapes,
+// plus a missing outer key, a missing inner key, a value mismatch, and a
+// non-map attribute.
+func TestMapEntryMismatchReportsEachFailure(t *testing.T) {
+	t.Parallel()
+
+	attributes := map[string]interface{}{
+		"endpoints": map[string]string{"s3": "https://s3.fr-par.scw.cloud"},
+		"labels":    map[string]interface{}{"team": "platform", "tier": 1},
+		"name":      "fixture",
+	}
+	for _, tc := range []struct {
+		key, mapKey, want string
+		errContains       string
+	}{
+		{"endpoints", "s3", "https://s3.fr-par.scw.cloud", ""},
+		{"labels", "team", "platform", ""},
+		{"missing", "s3", "x", "is not set"},
+		{"endpoints", "dynamodb", "x", `no "dynamodb" entry`},
+		{"labels", "team", "security", `want "security"`},
+		{"labels", "tier", "1", `want "1"`},
+		{"name", "s3", "x", "to be a map"},
+	} {
+		err := mapEntryMismatch(attributes, tc.key, tc.mapKey, tc.want)
+		if tc.errContains == "" {
+			if err != nil {
+				t.Errorf("%s[%q]: unexpected mismatch: %v", tc.key, tc.mapKey, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.errContains) {
+			t.Errorf("%s[%q]: expected an error containing %q, got %v", tc.key, tc.mapKey, tc.errContains, err)
+		}
+	}
+}
+
+// TestNumberMismatchComparesExactly covers whole and fractional values in
+// both decoded forms, plus missing and non-numeric attributes.
+func TestNumberMismatchComparesExactly(t *testing.T) {
+	t.Parallel()
+
+	attributes := map[string]interface{}{
+		"wait_timer":  float64(30),
+		"from_number": json.Number("30"),
+		"fraction":    0.5,
+		"label":       "30",
+	}
+	for _, tc := range []struct {
+		key     string
+		want    float64
+		matches bool
+	}{
+		{"wait_timer", 30, true},
+		{"wait_timer", 31, false},
+		{"from_number", 30, true},
+		{"fraction", 0.5, true},
+		{"fraction", 0, false},
+		{"label", 30, false},
+		{"missing", 30, false},
+	} {
+		err := numberMismatch(attributes, tc.key, tc.want)
+		if tc.matches && err != nil {
+			t.Errorf("%s = %v: unexpected mismatch: %v", tc.key, tc.want, err)
+		}
+		if !tc.matches && err == nil {
+			t.Errorf("%s = %v: expected a mismatch", tc.key, tc.want)
+		}
+	}
+}
+
+// TestFakeS3BucketNamesAreUniqueUnderParallelism starts many fake servers at
+// once and checks every bucket name is distinct and valid.
+func TestFakeS3BucketNamesAreUniqueUnderParallelism(t *testing.T) {
+	t.Parallel()
+
+	const servers = 16
+	var (
+		mu    sync.Mutex
+		names = map[string]bool{}
+	)
+	t.Run("start", func(t *testing.T) {
+		for i := 0; i < servers; i++ {
+			t.Run(fmt.Sprintf("server-%d", i), func(t *testing.T) {
+				t.Parallel()
+
+				server, bucket := startFakeS3(t)
+				defer server.Close()
+				if err := validateBucketName(bucket); err != nil {
+					t.Error(err)
+				}
+				mu.Lock()
+				defer mu.Unlock()
+				if names[bucket] {
+					t.Errorf("bucket name %s was generated twice", bucket)
+				}
+				names[bucket] = true
+			})
+		}
+	})
+	if len(names) != servers {
+		t.Fatalf("expected %d distinct bucket names, got %d", servers, len(names))
+	}
+}
+
+// TestValidateBucketNameAppliesS3Rules pins the naming rules with accepted
+// and rejected specimens.
+func TestValidateBucketNameAppliesS3Rules(t *testing.T) {
+	t.Parallel()
+
+	for _, valid := range []string{"abc", "fake-s3-0123abcd", "df12-tfstate", "a.b-c", strings.Repeat("a", 63)} {
+		if err := validateBucketName(valid); err != nil {
+			t.Errorf("expected %q to be valid: %v", valid, err)
+		}
+	}
+	for _, invalid := range []string{"ab", strings.Repeat("a", 64), "Upper", "under_score", "-leading", "trailing-", "double..dot"} {
+		if err := validateBucketName(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+// TestSingleListObjectChecksShape covers the empty, multi-element, and
+// well-formed shapes firstObject sees in plan JSON.
+func TestSingleListObjectChecksShape(t *testing.T) {
+	block := map[string]interface{}{"required_approvals": float64(2)}
+	cases := []struct {
+		name    string
+		value   interface{}
+		wantErr string
+	}{
+		{name: "single", value: []interface{}{block}},
+		{name: "empty", value: []interface{}{}, wantErr: "empty list"},
+		{name: "multiple", value: []interface{}{block, block}, wantErr: "got 2"},
+		{name: "not a list", value: block, wantErr: "list of blocks"},
+		{name: "not an object", value: []interface{}{"main"}, wantErr: "hold an object"},
+		{name: "missing", value: nil, wantErr: "list of blocks"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			object, err := singleListObject(map[string]interface{}{"reviews": tc.value}, "reviews")
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected a block, got %v", err)
+				}
+				if !reflect.DeepEqual(object, block) {
+					t.Fatalf("expected %#v, got %#v", block, object)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// moduleDependencyGraph maps each module call in body to the modules it
+// depends on, through either module.* references or explicit depends_on.
+func moduleDependencyGraph(body *hclsyntax.Body) map[string][]string {
+	graph := map[string][]string{}
+	for _, block := range body.Blocks {
+		if block.Type != "module" || len(block.Labels) == 0 {
+			continue
+		}
+
+		seen := map[string]bool{}
+		for _, attr := range block.Body.Attributes {
+			for _, traversal := range attr.Expr.Variables() {
+				if dependency, ok := moduleReference(traversal); ok {
+					seen[dependency] = true
+				}
+			}
+		}
+
+		dependencies := make([]string, 0, len(seen))
+		for dependency := range seen {
+			dependencies = append(dependencies, dependency)
+		}
+		sort.Strings(dependencies)
+		graph[block.Labels[0]] = dependencies
+	}
+	return graph
+}
+
+func moduleReference(traversal hcl.Traversal) (string, bool) {
+	if len(traversal) < 2 || traversal.RootName() != "module" {
+		return "", false
+	}
+	step, ok := traversal[1].(hcl.TraverseAttr)
+	if !ok {
+		return "", false
+	}
+	return step.Name, true
+}
+
+// destroyOrder returns module names so that every module precedes the modules
+// it depends on, which is the order tofu destroys them in. It fails when the
+// graph contains a cycle or references an undeclared module.
+func destroyOrder(graph map[string][]string) ([]string, error) {
+	dependents := map[string]int{}
+	for name, dependencies := range graph {
+		if _, ok := dependents[name]; !ok {
+			dependents[name] = 0
+		}
+		for _, dependency := range dependencies {
+			if _, declared := graph[dependency]; !declared {
+				return nil, fmt.Errorf("module %s depends on undeclared module %s", name, dependency)
+			}
+			dependents[dependency]++
+		}
+	}
+
+	var ready []string
+	for name, count := range dependents {
+		if count == 0 {
+			ready = append(ready, name)
+		}
+	}
+	sort.Strings(ready)
+
+	order := make([]string, 0, len(graph))
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, name)
+		for _, dependency := range graph[name] {
+			dependents[dependency]--
+			if dependents[dependency] == 0 {
+				ready = append(ready, dependency)
+				sort.Strings(ready)
+			}
+		}
+	}
+
+	if len(order) != len(graph) {
+		return nil, fmt.Errorf("module dependency cycle detected; destroyed %v of %d modules", order, len(graph))
+	}
+	return order, nil
+}
+
+func assertDestroyOrderRespectsDependencies(t *testing.T, graph map[string][]string, order []string) {
+	t.Helper()
+
+	position := make(map[string]int, len(order))
+	for index, name := range order {
+		position[name] = index
+	}
+	for name, dependencies := range graph {
+		for _, dependency := range dependencies {
+			if position[name] > position[dependency] {
+				t.Fatalf("module %s must be destroyed before its dependency %s, got order %v", name, dependency, order)
+			}
+		}
+	}
+}
+
+// TestBackendBlockDeclared ensures the root stack opts into the S3 backend so
+// remote state can be configured via a tfbackend file.
+func TestBackendBlockDeclared(t *testing.T) {
+	parser := hclparse.NewParser()
+	file, diag := parser.ParseHCLFile(repoPath(t, "..", "backend.tf"))
+	if diag.HasErrors() {
+		t.Fatalf("parse backend.tf: %s", diag.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		t.Fatalf("backend.tf unexpected body type %T", file.Body)
+	}
+
+	if findBackendS3Block(body) == nil {
+		t.Fatalf("expected terraform backend \"s3\" block in backend.tf")
+	}
+}
+
+// TestBackendBlockRequestsEncryption ensures remote state is written with
+// server-side encryption rather than trusting each bucket's defaults.
+func TestBackendBlockRequestsEncryption(t *testing.T) {
+	backend := loadBackendS3Block(t, filepath.Join("..", "backend.tf"))
+	if err := backendEncryptionViolation(backend); err != nil {
+		fatalAt(t, backend.DefRange(), "backend.tf: %v", err)
+	}
+}
+
+// TestMergedBackendConfigsRequestEncryption merges each committed tfbackend
+// file over backend.tf, as init does with -backend-config, and requires the
+// result to still request encryption; backend.tf alone cannot show that.
+func TestMergedBackendConfigsRequestEncryption(t *testing.T) {
+	backend := loadBackendS3Block(t, filepath.Join("..", "backend.tf"))
+	specimens, err := filepath.Glob(repoPath(t, backendSpecimenGlob))
+	if err != nil {
+		t.Fatalf("glob backend specimens: %v", err)
+	}
+	if len(specimens) == 0 {
+		t.Fatalf("expected at least one tfbackend specimen under backend/")
+	}
+	for _, path := range specimens {
+		if err := mergedEncryptionViolation(backend, loadBackendConfig(t, path)); err != nil {
+			t.Errorf("%s merged over backend.tf: %v", path, err)
+		}
+	}
+}
+
+// TestMergedEncryptionViolationHonoursOverrides checks a tfbackend setting
+// wins over backend.tf, and an omitted one defers to it.
+func TestMergedEncryptionViolationHonoursOverrides(t *testing.T) {
+	enabled, disabled := true, false
+	cases := map[string]struct {
+		backend  string
+		override *bool
+		wantErr  bool
+	}{
+		"inherited":          {backend: `encrypt = true`},
+		"disabled in file":   {backend: `encrypt = true`, override: &disabled, wantErr: true},
+		"enabled in file":    {backend: ``, override: &enabled},
+		"missing everywhere": {backend: ``, wantErr: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			body := parseInlineHCL(t, "terraform {\n  backend \"s3\" {\n    "+tc.backend+"\n  }\n}\n")
+			err := mergedEncryptionViolation(findBackendS3Block(body), backendconfig.Config{Encrypt: tc.override})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestBackendBlockHasNoInlineConfig keeps estate-specific settings out of the
+// shared stack; they belong in the tfbackend file passed at init.
+func TestBackendBlockHasNoInlineConfig(t *testing.T) {
+	backend := loadBackendS3Block(t, filepath.Join("..", "backend.tf"))
+	if inline := backendInlineConfig(backend); len(inline) > 0 {
+		fatalAt(t, backend.DefRange(), "backend.tf sets %s inline; move them to a tfbackend file", strings.Join(inline, ", "))
+	}
+}
+
+// TestBackendInlineConfigFlagsBucket checks the specimen backend.tf that
+// hardcodes a bucket is rejected.
+func TestBackendInlineConfigFlagsBucket(t *testing.T) {
+	backend := loadBackendS3Block(t, filepath.Join("testdata", "backend_inline", "backend.tf"))
+	got := backendInlineConfig(backend)
+	if len(got) != 1 || got[0] != "bucket" {
+		t.Fatalf("expected the inline bucket to be flagged, got %q", got)
+	}
+}
+
+// TestBackendEncryptionViolationRequiresTrue covers the missing, false, and
+// non-literal cases the backend check must reject.
+func TestBackendEncryptionViolationRequiresTrue(t *testing.T) {
+	cases := map[string]struct {
+		src     string
+		wantErr bool
+	}{
+		"encrypted": {src: `encrypt = true`},
+		"missing":   {src: ``, wantErr: true},
+		"disabled":  {src: `encrypt = false`, wantErr: true},
+		"variable":  {src: `encrypt = var.encrypt`, wantErr: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			body := parseInlineHCL(t, "terraform {\n  backend \"s3\" {\n    "+tc.src+"\n  }\n}\n")
+			err := backendEncryptionViolation(findBackendS3Block(body))
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestBackendTerraformRequirementsDeclared ensures backend.tf locks the OpenTofu
+// and GitHub provider versions expected by CI.
+func TestBackendTerraformRequirementsDeclared(t *testing.T) {
+	parser := hclparse.NewParser()
+	file, diag := parser.ParseHCLFile(repoPath(t, "..", "backend.tf"))
+	if diag.HasErrors() {
+		t.Fatalf("parse backend.tf: %s", diag.Error())
+	}
+
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		t.Fatalf("backend.tf unexpected body type %T", file.Body)
+	}
+
+	terraformBlock := findTerraformBlock(t, body, "backend.tf")
+	validateRequiredVersion(t, terraformBlock, "backend.tf")
+	requiredProviders := findRequiredProvidersBlock(t, terraformBlock, "backend.tf")
+	validateGitHubProvider(t, requiredProviders, "backend.tf")
+}
+
+// findBackendS3Block returns the backend "s3" block from any terraform block
+// in body, or nil when the stack does not declare one.
+func findBackendS3Block(body *hclsyntax.Body) *hclsyntax.Block {
+	for _, block := range body.Blocks {
+		if block.Type != "terraform" {
+			continue
+		}
+		if backend := findBlock(block.Body, "backend", "s3"); backend != nil {
+			return backend
+		}
+	}
+	return nil
+}
+
+// loadBackendS3Block parses the file at path and returns its backend "s3"
+// block, failing the test when there is none.
+func loadBackendS3Block(t *testing.T, path string) *hclsyntax.Block {
+	t.Helper()
+
+	path = repoPath(t, path)
+	parser := hclparse.NewParser()
+	file, diag := parser.ParseHCLFile(path)
+	if diag.HasErrors() {
+		t.Fatalf("parse %s: %s", path, diag.Error())
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		t.Fatalf("%s unexpected body type %T", path, file.Body)
+	}
+
+	backend := findBackendS3Block(body)
+	if backend == nil {
+		t.Fatalf("expected terraform backend \"s3\" block in %s", path)
+	}
+	return backend
+}
+
+// backendInlineSettings are the estate-specific backend attributes that must
+// come from a tfbackend file rather than the shared stack.
+var backendInlineSettings = []string{"bucket", "key", "region", "endpoints"}
+
+// backendInlineConfig lists the estate-specific settings backend declares
+// inline, in backendInlineSettings order.
+func backendInlineConfig(backend *hclsyntax.Block) []string {
+	var inline []string
+	for _, name := range backendInlineSettings {
+		if _, ok := backend.Body.Attributes[name]; ok {
+			inline = append(inline, name)
+		}
+	}
+	return inline
+}
+
+// backendEncryptionViolation reports why backend does not request
+// server-side encryption, or nil when it sets encrypt = true literally.
+func backendEncryptionViolation(backend *hclsyntax.Block) error {
+	attr, ok := backend.Body.Attributes["encrypt"]
+	if !ok {
+		return errors.New("backend \"s3\" must set encrypt = true")
+	}
+	value, diags := attr.Expr.Value(&hcl.EvalContext{})
+	if diags.HasErrors() || value.IsNull() || !value.Type().Equals(cty.Bool) {
+		return errors.New("backend \"s3\" encrypt must be the literal true")
+	}
+	if value.False() {
+		return errors.New("backend \"s3\" sets encrypt = false; remote state must be encrypted")
+	}
+	return nil
+}
+
+// mergedEncryptionViolation checks the encryption setting init ends up with
+// once cfg is passed as -backend-config: a setting in the tfbackend file
+// replaces backend.tf's, and an omitted one leaves backend.tf's in force.
+func mergedEncryptionViolation(backend *hclsyntax.Block, cfg backendconfig.Config) error {
+	if cfg.Encrypt == nil {
+		return backendEncryptionViolation(backend)
+	}
+	if !*cfg.Encrypt {
+		return errors.New("tfbackend sets encrypt = false, overriding backend.tf; remote state must be encrypted")
+	}
+	return nil
+}
+
+// findTerraformBlock returns the first terraform block in body; source names
+// the file or module in failure messages.
+func findTerraformBlock(t *testing.T, body *hclsyntax.Body, source string) *hclsyntax.Block {
+	t.Helper()
+
+	blk, err := backendcheck.TerraformBlock(body, source)
+	fatalOnHCLError(t, err)
+	return blk
+}
+
+// fatalOnHCLError fails the test with err, annotating its source range when
+// it carries one.
+func fatalOnHCLError(t *testing.T, err error) {
+	t.Helper()
+
+	var rangeErr *backendcheck.RangeError
+	switch {
+	case err == nil:
+	case errors.As(err, &rangeErr):
+		fatalAt(t, rangeErr.Range, "%s", rangeErr.Message)
+	default:
+		t.Fatal(err)
+	}
+}
+
+func validateRequiredVersion(t *testing.T, terraformBlock *hclsyntax.Block, source string) {
+	t.Helper()
+
+	const expectedRequiredVersion = ">= 1.10.7, < 2.0.0"
+	attr, ok := terraformBlock.Body.Attributes["required_version"]
+	if !ok {
+		fatalAt(t, terraformBlock.DefRange(), "expected terraform.required_version to be declared in %s", source)
+	}
+
+	if got := findAttributeString(t, terraformBlock, "required_version"); got != expectedRequiredVersion {
+		fatalAt(t, attr.SrcRange, "expected terraform.required_version %q in %s, got %q", expectedRequiredVersion, source, got)
+	}
+}
+
+// TestAllModulesPinRequiredVersion applies the backend.tf required_version
+// check to every module so none can float to an untested OpenTofu release.
+func TestAllModulesPinRequiredVersion(t *testing.T) {
+	for _, dir := range moduleDirs(t) {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			terraformBlock := findTerraformBlock(t, moduleTerraformBody(t, dir), dir)
+			validateRequiredVersion(t, terraformBlock, dir)
+		})
+	}
+}
+
+// moduleTerraformBody returns the body of the module file that declares the
+// terraform block, so modules may keep it in any top-level file.
+func moduleTerraformBody(t *testing.T, dir string) *hclsyntax.Body {
+	t.Helper()
+
+	for _, file := range parseModuleFiles(t, dir) {
+		if findBlock(file.body, "terraform") != nil {
+			return file.body
+		}
+	}
+	t.Fatalf("expected terraform block in module %s", dir)
+	return nil
+}
+
+func findRequiredProvidersBlock(t *testing.T, terraformBlock *hclsyntax.Block, source string) *hclsyntax.Block {
+	t.Helper()
+
+	blk, err := backendcheck.RequiredProvidersBlock(terraformBlock, source)
+	fatalOnHCLError(t, err)
+	return blk
+}
+
+func validateGitHubProvider(t *testing.T, requiredProviders *hclsyntax.Block, source string) {
+	t.Helper()
+
+	fatalOnHCLError(t, backendcheck.GitHubProvider(requiredProviders, source))
+}
+
+// TestAllModulesPinGitHubProvider applies the backend.tf provider check to
+// every module so none can accept an incompatible provider major.
+func TestAllModulesPinGitHubProvider(t *testing.T) {
+	for _, dir := range moduleDirs(t) {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			terraformBlock := findTerraformBlock(t, moduleTerraformBody(t, dir), dir)
+			requiredProviders := findRequiredProvidersBlock(t, terraformBlock, dir)
+			validateGitHubProvider(t, requiredProviders, dir)
+		})
+	}
+}
+
+// TestScalewayBackendConfigAssertsNoInlineSecrets guards the committed
+// tfbackend specimen against accidental credential leakage and regression of
+// the documented defaults.
+func TestScalewayBackendConfigAssertsNoInlineSecrets(t *testing.T) {
+	config := loadScalewayBackendConfig(t)
+
+	validateScalewayRequiredFields(t, config)
+	validateScalewayRequiredBooleans(t, config)
+	validateScalewayForbiddenCredentials(t, config)
+	validateScalewayOptionalSkipFlags(t, config)
+	validateBackendEncryptionKept(t, config)
+	validateBackendProfileOmitted(t, config)
+}
+
+// TestAwsBackendConfigValidates checks the AWS specimen may use DynamoDB
+// locking and a lockfile while still keeping credentials out of the file.
+func TestAwsBackendConfigValidates(t *testing.T) {
+	config := loadBackendConfig(t, filepath.Join("..", "backend", "aws.tfbackend"))
+
+	validateAWSRequiredFields(t, config)
+	validateAWSForbiddenCredentials(t, config)
+	validateBackendEncryptionKept(t, config)
+	validateBackendProfileOmitted(t, config)
+}
+
+// TestRenderedScalewayBackendPassesValidators checks the backendgen output
+// satisfies the same validators as the committed specimen.
+func TestRenderedScalewayBackendPassesValidators(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := backendconfig.Scaleway("df12-tfstate", "fr-par", "foo", "main")
+	if err != nil {
+		t.Fatalf("build Scaleway backend: %v", err)
+	}
+	rendered, err := backendconfig.Render(cfg, "Rendered for validation.")
+	if err != nil {
+		t.Fatalf("render Scaleway backend: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "scaleway.tfbackend")
+	if err := os.WriteFile(path, rendered, 0o600); err != nil {
+		t.Fatalf("write rendered backend: %v", err)
+	}
+
+	config := loadBackendConfig(t, path)
+	if config.Key != "estates/foo/main/terraform.tfstate" {
+		t.Fatalf("expected key for estate foo stack main, got %q", config.Key)
+	}
+	validateScalewayRequiredFields(t, config)
+	validateScalewayRequiredBooleans(t, config)
+	validateScalewayForbiddenCredentials(t, config)
+	validateScalewayOptionalSkipFlags(t, config)
+	validateBackendEncryptionKept(t, config)
+	validateBackendProfileOmitted(t, config)
+}
+
+// TestRenderRefusesCredentials keeps backendgen from ever writing a secret,
+// even when a caller populates one on the config.
+func TestRenderRefusesCredentials(t *testing.T) {
+	t.Parallel()
+
+	secret := "not-a-real-secret"
+	cases := map[string]func(*scalewayBackendConfig){
+		"access_key":    func(cfg *scalewayBackendConfig) { cfg.AccessKey = &secret },
+		"secret_key":    func(cfg *scalewayBackendConfig) { cfg.SecretKey = &secret },
+		"session_token": func(cfg *scalewayBackendConfig) { cfg.SessionToken = &secret },
+		"profile":       func(cfg *scalewayBackendConfig) { cfg.Profile = &secret },
+	}
+	for field, set := range cases {
+		t.Run(field, func(t *testing.T) {
+			cfg, err := backendconfig.Scaleway("df12-tfstate", "fr-par", "foo", "main")
+			if err != nil {
+				t.Fatalf("build Scaleway backend: %v", err)
+			}
+			set(&cfg)
+			rendered, err := backendconfig.Render(cfg, "")
+			if err == nil {
+				t.Fatalf("expected %s to be refused, rendered:\n%s", field, rendered)
+			}
+			if !strings.Contains(err.Error(), field) {
+				t.Fatalf("expected the error to name %s, got %v", field, err)
+			}
+		})
+	}
+}
+
+// TestAwsBackendValidatorsRejectInlineKeysAndUnknownRegions proves the AWS
+// rules fire on embedded keys and on a non-AWS region.
+func TestAwsBackendValidatorsRejectInlineKeysAndUnknownRegions(t *testing.T) {
+	config := loadBackendConfig(t, filepath.Join("testdata", "backend", "aws_inline_keys.tfbackend"))
+	if err := backendcheck.InlineCredentialViolation(config); err == nil {
+		t.Fatalf("expected an AWS backend embedding access keys to be rejected")
+	}
+
+	scaleway := loadScalewayBackendConfig(t)
+	if err := backendcheck.AWSRegionViolation(scaleway); err == nil {
+		t.Fatalf("expected Scaleway region %q to be rejected as an AWS region", scaleway.Region)
+	}
+}
+
+// TestBackendKeysFollowConvention checks the state key layout of every
+// committed tfbackend specimen.
+func TestBackendKeysFollowConvention(t *testing.T) {
+	for name, body := range loadAllBackendConfigs(t) {
+		t.Run(name, func(t *testing.T) {
+			assertBackendKeyConvention(t, decodeBackendConfig(t, name, body).Key)
+		})
+	}
+}
+
+// TestBackendKeyConventionSpecimens proves the key check accepts the
+// conventional specimen and rejects the non-standard one.
+func TestBackendKeyConventionSpecimens(t *testing.T) {
+	conventional := loadBackendConfig(t, filepath.Join("testdata", "backend", "key_conventional.tfbackend"))
+	if err := backendcheck.KeyViolation(conventional.Key); err != nil {
+		t.Fatalf("expected conventional key to pass: %v", err)
+	}
+
+	nonstandard := loadBackendConfig(t, filepath.Join("testdata", "backend", "key_nonstandard.tfbackend"))
+	if err := backendcheck.KeyViolation(nonstandard.Key); err == nil {
+		t.Fatalf("expected key %q to be rejected", nonstandard.Key)
+	}
+}
+
+// TestScalewayEndpointValidatorRejectsExtraEndpoints proves the endpoint
+// guard fires on a specimen that overrides more than s3.
+func TestScalewayEndpointValidatorRejectsExtraEndpoints(t *testing.T) {
+	config := loadBackendConfig(t, filepath.Join("testdata", "backend", "scaleway_extra_endpoint.tfbackend"))
+
+	err := backendcheck.ScalewayEndpointViolation(config)
+	if err == nil {
+		t.Fatalf("expected a Scaleway backend with a dynamodb endpoint to be rejected")
+	}
+	if !strings.Contains(err.Error(), "dynamodb") {
+		t.Fatalf("expected the error to name the unexpected endpoint, got %v", err)
+	}
+}
+
+// TestScalewayRegionEndpointViolationRejectsMismatches proves the endpoint
+// must be the HTTPS host for the configured region.
+func TestScalewayRegionEndpointViolationRejectsMismatches(t *testing.T) {
+	mismatched := loadBackendConfig(t, filepath.Join("testdata", "backend", "scaleway_region_mismatch.tfbackend"))
+	err := backendcheck.ScalewayRegionEndpointViolation(mismatched)
+	if err == nil || !strings.Contains(err.Error(), "https://s3.nl-ams.scw.cloud") {
+		t.Fatalf("expected the fr-par endpoint to be rejected for region nl-ams, got %v", err)
+	}
+
+	plain := mismatched
+	plain.Endpoints = map[string]string{"s3": "http://s3.nl-ams.scw.cloud"}
+	if err := backendcheck.ScalewayRegionEndpointViolation(plain); err == nil || !strings.Contains(err.Error(), "must use https") {
+		t.Fatalf("expected a plain-HTTP endpoint to be rejected, got %v", err)
+	}
+
+	matched := mismatched
+	matched.Endpoints = map[string]string{"s3": "https://s3.nl-ams.scw.cloud"}
+	if err := backendcheck.ScalewayRegionEndpointViolation(matched); err != nil {
+		t.Fatalf("expected the nl-ams endpoint to pass: %v", err)
+	}
+}
+
+// TestBackendConfigsOmitProfile ensures no committed tfbackend names an AWS
+// profile, so credentials always come from the environment rather than the
+// operator's local AWS configuration.
+func TestBackendConfigsOmitProfile(t *testing.T) {
+	for name, body := range loadAllBackendConfigs(t) {
+		t.Run(name, func(t *testing.T) {
+			validateBackendProfileOmitted(t, decodeBackendConfig(t, name, body))
+		})
+	}
+}
+
+// TestBackendConfigsAvoidInterpolation ensures no committed tfbackend writes
+// ${...} expecting tofu to expand it; backend files are taken literally.
+func TestBackendConfigsAvoidInterpolation(t *testing.T) {
+	for name, body := range loadAllBackendConfigs(t) {
+		t.Run(name, func(t *testing.T) {
+			for _, err := range backendcheck.InterpolationViolations(body.(*hclsyntax.Body)) {
+				var rangeErr *backendcheck.RangeError
+				if errors.As(err, &rangeErr) {
+					annotate(t, rangeErr.Range.Filename, rangeErr.Range.Start.Line, rangeErr.Message)
+				}
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestInterpolationViolationsSpecimens proves the interpolation check names
+// the file and each interpolated attribute, and passes a literal specimen.
+func TestInterpolationViolationsSpecimens(t *testing.T) {
+	parse := func(name string) *hclsyntax.Body {
+		path := repoPath(t, "testdata", "backend", name)
+		file, diags := hclparse.NewParser().ParseHCLFile(path)
+		if diags.HasErrors() {
+			t.Fatalf("parse %s: %s", path, diags.Error())
+		}
+		return file.Body.(*hclsyntax.Body)
+	}
+
+	errs := backendcheck.InterpolationViolations(parse("interpolated.tfbackend"))
+	if len(errs) != 2 {
+		t.Fatalf("expected two interpolated attributes, got %v", errs)
+	}
+	for i, attr := range []string{"access_key", "endpoints"} {
+		if msg := errs[i].Error(); !strings.Contains(msg, "interpolated.tfbackend: "+attr+" uses interpolation") {
+			t.Errorf("expected the file and %s to be named, got %q", attr, msg)
+		}
+	}
+
+	if errs := backendcheck.InterpolationViolations(parse("interpolation_free.tfbackend")); len(errs) != 0 {
+		t.Fatalf("expected the literal specimen to pass, got %v", errs)
+	}
+}
+
+// TestLoadAllBackendConfigsFindsSpecimens checks every tfbackend under
+// backend/ is returned, keyed by provider name.
+func TestLoadAllBackendConfigsFindsSpecimens(t *testing.T) {
+	configs := loadAllBackendConfigs(t)
+
+	specimens, err := filepath.Glob(repoPath(t, backendSpecimenGlob))
+	if err != nil {
+		t.Fatalf("glob backend specimens: %v", err)
+	}
+	if len(configs) != len(specimens) {
+		t.Fatalf("expected %d backend configs, got %d", len(specimens), len(configs))
+	}
+	for _, name := range []string{"aws", "scaleway"} {
+		if _, ok := configs[name]; !ok {
+			t.Errorf("expected backend specimen %q to be loaded", name)
+		}
+	}
+}
+
+// TestBackendProfileValidatorRejectsProfile proves the profile guard fires on
+// a specimen that sets one.
+func TestBackendProfileValidatorRejectsProfile(t *testing.T) {
+	config := loadBackendConfig(t, filepath.Join("testdata", "backend", "profile.tfbackend"))
+
+	if err := backendcheck.ProfileViolation(config); err == nil {
+		t.Fatalf("expected a backend config declaring profile to be rejected")
+	}
+}
+
+// TestBackendInitAgainstFakeS3 exercises backend init using the Scaleway
+// template against a local S3-compatible server to guard backend wiring.
+func TestBackendInitAgainstFakeS3(t *testing.T) {
+	fakeS3, bucket := startFakeS3(t)
+	defer fakeS3.Close()
+
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	opts := backendInitOptions(t, copyStackToTemp(t, ".."), config)
+
+	if _, err := terraform.InitE(t, opts); err != nil {
+		t.Fatalf("tofu init with fake S3 backend: %v", err)
+	}
+}
+
+// TestBackendInitToleratesTransientFailures fails the first requests for the
+// seeded state object with 503s and checks init and a state read retry
+// through them.
+func TestBackendInitToleratesTransientFailures(t *testing.T) {
+	failures := newTransientFailures(map[string]int{fakeS3StateKey: 2})
+	fakeS3, bucket, _ := startFakeS3WithOptions(t, fakeS3Options{
+		seedObjects:       map[string][]byte{fakeS3StateKey: seededStateSnapshot},
+		transientFailures: failures,
+	})
+	defer fakeS3.Close()
+
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	opts := backendInitOptions(t, copyStackToTemp(t, ".."), config)
+
+	if _, err := terraform.InitE(t, opts); err != nil {
+		t.Fatalf("tofu init under transient S3 failures: %v", err)
+	}
+	// Reading an output guarantees the state object itself was fetched.
+	if marker, err := terraform.OutputE(t, opts, "seeded_marker"); err != nil || marker != "from-fake-s3" {
+		t.Fatalf("read seeded state under transient S3 failures: %q, %v", marker, err)
+	}
+	if got := failures.servedFor(fakeS3StateKey); got != 2 {
+		t.Fatalf("expected tofu to retry through two injected failures, served %d", got)
+	}
+}
+
+// TestBackendInitRetriesOutlastInjectedFailures caps the backend's own SDK
+// retries and injects more 503s than it absorbs: the run fails without
+// command-level retries and succeeds once terraformOptionsWithRetry is used.
+func TestBackendInitRetriesOutlastInjectedFailures(t *testing.T) {
+	const injected = 3
+	cases := map[string]struct {
+		retry   bool
+		wantErr bool
+	}{
+		"without retry": {wantErr: true},
+		"with retry":    {retry: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			failures := newTransientFailures(map[string]int{fakeS3StateKey: injected})
+			fakeS3, bucket, _ := startFakeS3WithOptions(t, fakeS3Options{
+				seedObjects:       map[string][]byte{fakeS3StateKey: seededStateSnapshot},
+				transientFailures: failures,
+			})
+			defer fakeS3.Close()
+
+			config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+			opts := backendInitOptions(t, copyStackToTemp(t, ".."), config)
+			// One SDK retry, so two attempts per request: fewer than the
+			// injected failures, which then outlast the backend and reach tofu.
+			opts.BackendConfig["max_retries"] = 1
+			if tc.retry {
+				opts = terraformOptionsWithRetry(opts, 5)
+			}
+
+			_, err := terraform.InitE(t, opts)
+			if err == nil {
+				_, err = terraform.OutputE(t, opts, "seeded_marker")
+			}
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("expected error %v under %d injected failures, got %v", tc.wantErr, injected, err)
+			}
+		})
 	}
 }
 
-func resolveFixture(t *testing.T, pathSegments ...string) string {
-	t.Helper()
+// TestBackendInitFromBackendConfigFile writes the backend config to a
+// tfbackend file and inits with -backend-config=<file>, the way production
+// runs, rather than passing individual key=value flags.
+func TestBackendInitFromBackendConfigFile(t *testing.T) {
+	fakeS3, bucket := startFakeS3(t)
+	defer fakeS3.Close()
 
-	target := filepath.Join(pathSegments...)
-	absPath, err := filepath.Abs(target)
-	if err != nil {
-		t.Fatalf("resolve fixture %s: %v", target, err)
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	configPath := writeBackendConfigFile(t, config)
+	opts := backendInitOptions(t, copyStackToTemp(t, ".."), config)
+	// A nil value makes terratest pass the key on its own, which tofu treats
+	// as a path to a backend config file.
+	opts.BackendConfig = map[string]interface{}{configPath: nil}
+
+	if _, err := terraform.InitE(t, opts); err != nil {
+		t.Fatalf("tofu init with backend config file %s: %v", configPath, err)
 	}
-	return absPath
 }
 
-func terraformBinary() string {
-	if binary := strings.TrimSpace(os.Getenv("TERRAFORM_BINARY")); binary != "" {
-		return binary
+// TestBackendInitDetectsExistingState seeds the fake bucket with a state
+// snapshot so init follows the common path where remote state already exists,
+// then reads an output back to prove the snapshot was picked up untouched.
+func TestBackendInitDetectsExistingState(t *testing.T) {
+	fakeS3, bucket, client := startFakeS3WithOptions(t, fakeS3Options{
+		seedObjects: map[string][]byte{fakeS3StateKey: seededStateSnapshot},
+	})
+	defer fakeS3.Close()
+
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	opts := backendInitOptions(t, copyStackToTemp(t, ".."), config)
+
+	if _, err := terraform.InitE(t, opts); err != nil {
+		t.Fatalf("tofu init against seeded fake S3 backend: %v", err)
 	}
-	return "tofu"
-}
 
-// assertBoolTrue fails the test if the given attribute is not a true boolean.
-func assertBoolTrue(t *testing.T, attributes map[string]interface{}, key, message string) {
-	t.Helper()
-	value, ok := attributes[key].(bool)
-	if !ok || !value {
-		t.Fatalf("%s, got %#v", message, attributes[key])
+	marker, err := terraform.OutputE(t, opts, "seeded_marker")
+	if err != nil {
+		t.Fatalf("read output from seeded state: %v", err)
+	}
+	if marker != "from-fake-s3" {
+		t.Fatalf("expected seeded_marker output %q, got %q", "from-fake-s3", marker)
 	}
-}
 
-// assertBoolFalse fails the test if the given attribute is not a false boolean.
-func assertBoolFalse(t *testing.T, attributes map[string]interface{}, key, message string) {
-	t.Helper()
-	value, ok := attributes[key].(bool)
-	if !ok || value {
-		t.Fatalf("%s, got %#v", message, attributes[key])
+	stored := readFakeS3Object(t, client, bucket, config.Key)
+	if !bytes.Equal(stored, seededStateSnapshot) {
+		t.Fatalf("init must not rewrite existing remote state, got %s", stored)
 	}
 }
 
-// TestRepositoryModuleDefaults validates the default merge strategy logic using terraform
-// plan output so we avoid hitting the GitHub API. The fixture config parallels CI usage.
-func TestRepositoryModuleDefaults(t *testing.T) {
-	options := terraformOptions(t, "..", "modules", "repository", "tests", "fixture")
+// TestBackendApplyWritesStateToFakeS3 closes the loop on the init tests by
+// applying a provider-free stack and confirming tofu persisted the resulting
+// state at the configured key.
+func TestBackendApplyWritesStateToFakeS3(t *testing.T) {
+	fakeS3, bucket, client := startFakeS3WithOptions(t, fakeS3Options{})
+	defer fakeS3.Close()
 
-	planStruct := terraform.InitAndPlanAndShowWithStruct(t, options)
-	repoAddress := "module.repository.github_repository.this"
-	plannedRepo, exists := planStruct.ResourcePlannedValuesMap[repoAddress]
-	if !exists {
-		t.Fatalf("expected repository resource %s to be planned", repoAddress)
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	opts := backendInitOptions(t, copyStackToTemp(t, filepath.Join("testdata", "backend_apply")), config)
+
+	if _, err := terraform.InitAndApplyE(t, opts); err != nil {
+		t.Fatalf("tofu apply with fake S3 backend: %v", err)
 	}
 
-	assertBoolTrue(t, plannedRepo.AttributeValues, "allow_squash_merge", "expected squash merge to remain enabled")
-	assertBoolFalse(t, plannedRepo.AttributeValues, "allow_merge_commit", "merge commits must stay disabled")
-	assertBoolFalse(t, plannedRepo.AttributeValues, "allow_rebase_merge", "rebase merges must stay disabled")
-	assertBoolTrue(t, plannedRepo.AttributeValues, "delete_branch_on_merge", "delete_branch_on_merge should default to true")
+	var state struct {
+		Resources []struct {
+			Type string `json:"type"`
+			Name string `json:"name"`
+		} `json:"resources"`
+	}
+	stored := readFakeS3Object(t, client, bucket, config.Key)
+	if err := json.Unmarshal(stored, &state); err != nil {
+		t.Fatalf("decode state written to fake S3: %v", err)
+	}
+	if len(state.Resources) != 1 || state.Resources[0].Type != "terraform_data" || state.Resources[0].Name != "marker" {
+		t.Fatalf("expected state at %s to record terraform_data.marker, got %s", config.Key, stored)
+	}
 }
 
-// TestRepositoryModuleRejectsMissingMergePaths ensures the validation guard blocks
-// configurations that disable every merge mode.
-func TestRepositoryModuleRejectsMissingMergePaths(t *testing.T) {
-	options := terraformOptions(t, "..", "modules", "repository", "tests", "fixture_disable_merges")
+// TestBackendApplyHonoursWorkspaceKeyPrefix applies the same stack in the
+// default workspace and in a named one, and checks the named workspace's
+// state lands under the env:/<workspace>/ prefix rather than the base key.
+func TestBackendApplyHonoursWorkspaceKeyPrefix(t *testing.T) {
+	fakeS3, bucket, client := startFakeS3WithOptions(t, fakeS3Options{})
+	defer fakeS3.Close()
+
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	opts := backendInitOptions(t, copyStackToTemp(t, filepath.Join("testdata", "backend_apply")), config)
+
+	if _, err := terraform.InitAndApplyE(t, opts); err != nil {
+		t.Fatalf("tofu apply in the default workspace: %v", err)
+	}
+	if _, err := terraform.WorkspaceSelectOrNewE(t, opts, "staging"); err != nil {
+		t.Fatalf("select staging workspace: %v", err)
+	}
+	if _, err := terraform.ApplyE(t, opts); err != nil {
+		t.Fatalf("tofu apply in the staging workspace: %v", err)
+	}
 
-	if _, err := terraform.InitAndPlanE(t, options); err == nil {
-		t.Fatalf("expected plan to fail when all merge strategies are disabled")
+	defaultKey := workspaceStateKey(config.Key, "default")
+	stagingKey := workspaceStateKey(config.Key, "staging")
+	if defaultKey == stagingKey {
+		t.Fatalf("expected workspace keys to differ, both are %s", defaultKey)
+	}
+	for _, key := range []string{defaultKey, stagingKey} {
+		if stored := readFakeS3Object(t, client, bucket, key); !bytes.Contains(stored, []byte(`"marker"`)) {
+			t.Fatalf("expected state at %s to record terraform_data.marker, got %s", key, stored)
+		}
 	}
 }
 
-// TestRepositoryModuleRejectsDisallowedMergeModes ensures the guardrails block
-// attempts to re-enable merge commits or rebase merges.
-func TestRepositoryModuleRejectsDisallowedMergeModes(t *testing.T) {
-	options := terraformOptions(t, "..", "modules", "repository", "tests", "fixture_enable_disallowed_merge")
+// TestWorkspaceStateKeyAddsPrefixForNamedWorkspaces pins the S3 backend's
+// workspace key layout that the estate key convention has to live with.
+func TestWorkspaceStateKeyAddsPrefixForNamedWorkspaces(t *testing.T) {
+	t.Parallel()
 
-	if _, err := terraform.InitAndPlanE(t, options); err == nil {
-		t.Fatalf("expected plan to fail when merge commits or rebase merges are enabled")
+	key := "estates/foo/main/terraform.tfstate"
+	if got := workspaceStateKey(key, "default"); got != key {
+		t.Fatalf("expected the default workspace to use %s, got %s", key, got)
+	}
+	if got, want := workspaceStateKey(key, "staging"), "env:/staging/"+key; got != want {
+		t.Fatalf("expected %s, got %s", want, got)
 	}
 }
 
-// TestBranchModuleRequiresStatusChecks ensures strict status checks carry contexts and
-// conversation resolution is force-enabled.
-func TestBranchModuleRequiresStatusChecks(t *testing.T) {
-	options := terraformOptions(t, "..", "modules", "branch", "tests", "fixture")
+// TestBackendReplanAfterApplyDestroysNothing seeds remote state by applying
+// a stack to the fake S3 backend, then re-plans it from a fresh workspace and
+// checks the plan is a no-op that deletes nothing.
+func TestBackendReplanAfterApplyDestroysNothing(t *testing.T) {
+	fakeS3, bucket, _ := startFakeS3WithOptions(t, fakeS3Options{})
+	defer fakeS3.Close()
 
-	planStruct := terraform.InitAndPlanAndShowWithStruct(t, options)
-	protectionAddress := "module.branch.github_branch_protection.this"
-	plannedProtection, exists := planStruct.ResourcePlannedValuesMap[protectionAddress]
-	if !exists {
-		t.Fatalf("expected branch protection resource %s to be planned", protectionAddress)
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	stack := filepath.Join("testdata", "backend_no_destroy")
+	seed := backendInitOptions(t, copyStackToTemp(t, stack), config)
+	if _, err := terraform.InitAndApplyE(t, seed); err != nil {
+		t.Fatalf("seed state on fake S3 backend: %v", err)
 	}
 
-	assertBoolTrue(t, plannedProtection.AttributeValues, "require_conversation_resolution", "conversation resolution guardrail should be true")
+	replan := backendInitOptions(t, copyStackToTemp(t, stack), config)
+	replan.PlanFilePath = planFilePath(t)
+	planStruct := tracedPlan(t, replan)
 
-	statusChecks, ok := plannedProtection.AttributeValues["required_status_checks"].([]interface{})
-	if !ok || len(statusChecks) == 0 {
-		t.Fatalf("expected required status checks to be populated, got %#v", plannedProtection.AttributeValues["required_status_checks"])
+	assertNoDestroys(t, planStruct)
+	for _, address := range []string{"terraform_data.marker", "terraform_data.guard"} {
+		assertPlannedAction(t, planStruct, address, "no-op")
 	}
 }
 
-// TestTeamModulePermissionMap verifies the module honours explicit repository permissions
-// and deduplicates maintainers when declared more than once.
-func TestTeamModulePermissionMap(t *testing.T) {
-	options := terraformOptions(t, "..", "modules", "team", "tests", "fixture")
-
-	planStruct := terraform.InitAndPlanAndShowWithStruct(t, options)
-	maintainerKey := "module.team.github_team_membership.maintainers[\"alice\"]"
-	if _, exists := planStruct.ResourcePlannedValuesMap[maintainerKey]; !exists {
-		t.Fatalf("expected maintainer membership %s to be planned", maintainerKey)
+// TestDestroyedAddressesFlagsDeletesAndReplacements checks deletes and
+// replacements are reported while creates, updates, and no-ops are not.
+func TestDestroyedAddressesFlagsDeletesAndReplacements(t *testing.T) {
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+		"a.create":  {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate}}},
+		"b.update":  {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}}},
+		"c.noop":    {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+		"d.delete":  {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete}}},
+		"e.replace": {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionCreate, tfjson.ActionDelete}}},
+	}}
+
+	got := destroyedAddresses(plan)
+	if want := []string{"d.delete", "e.replace"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected destroyed addresses %q, got %q", want, got)
 	}
+}
 
-	memberKey := "module.team.github_team_membership.members[\"bob\"]"
-	if _, exists := planStruct.ResourcePlannedValuesMap[memberKey]; !exists {
-		t.Fatalf("expected member mapping %s to be planned", memberKey)
+// TestPendingChangesListsDrift checks a plan of no-ops is empty and that a
+// drifting plan reports each change with its actions.
+func TestPendingChangesListsDrift(t *testing.T) {
+	noop := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+		"a.noop": {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+		"b.noop": {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+	}}
+	if got := pendingChanges(noop); len(got) != 0 {
+		t.Fatalf("expected a no-op plan to have no pending changes, got %q", got)
 	}
 
-	repoPermissionsAddress := "module.team.github_team_repository.default_permissions[\"fixture-repo\"]"
-	if _, exists := planStruct.ResourcePlannedValuesMap[repoPermissionsAddress]; !exists {
-		t.Fatalf("expected repository permission mapping %s to be created", repoPermissionsAddress)
+	drifting := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+		"a.noop":    {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}}},
+		"b.update":  {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionUpdate}}},
+		"c.replace": {Change: &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionDelete, tfjson.ActionCreate}}},
+	}}
+	got := pendingChanges(drifting)
+	if want := []string{"b.update: update", "c.replace: delete,create"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected pending changes %q, got %q", want, got)
 	}
 }
 
-// TestBackendBlockDeclared ensures the root stack opts into the S3 backend so
-// remote state can be configured via a tfbackend file.
-func TestBackendBlockDeclared(t *testing.T) {
-	parser := hclparse.NewParser()
-	file, diag := parser.ParseHCLFile(filepath.Join("..", "backend.tf"))
-	if diag.HasErrors() {
-		t.Fatalf("parse backend.tf: %s", diag.Error())
+// TestBackendLockfileSerialisesOperations holds the native S3 lockfile as if
+// another run were applying, checks a plan refuses to proceed, then releases
+// it and checks the next plan takes and drops the lock itself.
+func TestBackendLockfileSerialisesOperations(t *testing.T) {
+	fakeS3, bucket, client := startFakeS3WithOptions(t, fakeS3Options{})
+	defer fakeS3.Close()
+
+	config := fakeS3BackendConfig(t, fakeS3.URL, bucket)
+	config.UseLockfile = aws.Bool(true)
+	opts := backendInitOptions(t, copyStackToTemp(t, filepath.Join("testdata", "backend_apply")), config)
+	if _, err := terraform.InitE(t, opts); err != nil {
+		t.Fatalf("tofu init with lockfile backend: %v", err)
 	}
 
-	body, ok := file.Body.(*hclsyntax.Body)
-	if !ok {
-		t.Fatalf("backend.tf unexpected body type %T", file.Body)
+	lock := newFakeS3StateLock(client, bucket, config.Key)
+	lock.acquire(t, "other-operator@ci")
+	_, err := terraform.PlanE(t, opts)
+	if err == nil {
+		t.Fatalf("expected plan to fail while another operation holds the state lock")
+	}
+	if !strings.Contains(err.Error(), "lock") {
+		t.Fatalf("expected a state lock error, got %v", err)
+	}
+	if !lock.held(t) {
+		t.Fatalf("a blocked plan must not remove another operation's lock")
 	}
 
-	found := hasS3BackendBlock(body)
-	if !found {
-		t.Fatalf("expected terraform backend \"s3\" block in backend.tf")
+	lock.release(t)
+	if _, err := terraform.PlanE(t, opts); err != nil {
+		t.Fatalf("plan after releasing the lock: %v", err)
+	}
+	if lock.held(t) {
+		t.Fatalf("expected plan to release its own state lock")
 	}
 }
 
-// TestBackendTerraformRequirementsDeclared ensures backend.tf locks the OpenTofu
-// and GitHub provider versions expected by CI.
-func TestBackendTerraformRequirementsDeclared(t *testing.T) {
-	parser := hclparse.NewParser()
-	file, diag := parser.ParseHCLFile(filepath.Join("..", "backend.tf"))
-	if diag.HasErrors() {
-		t.Fatalf("parse backend.tf: %s", diag.Error())
-	}
+// fakeS3BackendConfig loads the committed Scaleway specimen and retargets it
+// at the fake S3 server so tests exercise the real flag set.
+func fakeS3BackendConfig(t *testing.T, endpoint, bucket string) scalewayBackendConfig {
+	t.Helper()
 
-	body, ok := file.Body.(*hclsyntax.Body)
-	if !ok {
-		t.Fatalf("backend.tf unexpected body type %T", file.Body)
+	config := loadScalewayBackendConfig(t)
+	config.Bucket = bucket
+	config.Key = fakeS3StateKey
+	config.Region = "us-east-1"
+	config.Endpoints = map[string]string{"s3": endpoint}
+	return config
+}
+
+// writeBackendConfigFile renders config as a tfbackend file in a temporary
+// directory and checks it decodes back to the same values before returning
+// its path.
+func writeBackendConfigFile(t *testing.T, config scalewayBackendConfig) string {
+	t.Helper()
+
+	file := hclwrite.NewEmptyFile()
+	gohcl.EncodeIntoBody(&config, file.Body())
+	path := filepath.Join(t.TempDir(), "fake-s3.tfbackend")
+	if err := os.WriteFile(path, file.Bytes(), 0o600); err != nil {
+		t.Fatalf("write backend config %s: %v", path, err)
 	}
 
-	terraformBlock := findTerraformBlock(t, body)
-	validateRequiredVersion(t, terraformBlock)
-	requiredProviders := findRequiredProvidersBlock(t, terraformBlock)
-	validateGitHubProvider(t, requiredProviders)
+	if roundTripped := loadBackendConfig(t, path); !reflect.DeepEqual(roundTripped, config) {
+		t.Fatalf("backend config did not round-trip through %s: want %#v, got %#v", path, config, roundTripped)
+	}
+	return path
 }
 
-func hasS3BackendBlock(body *hclsyntax.Body) bool {
-	for _, block := range body.Blocks {
-		if block.Type != "terraform" {
-			continue
-		}
-		if containsS3Backend(block) {
-			return true
-		}
+func backendInitOptions(t *testing.T, workspace string, config scalewayBackendConfig) *terraform.Options {
+	t.Helper()
+
+	requireTofu(t)
+	options := baseOptions(t, terraformBinary(), workspace)
+	// Backend tests apply directly; a plan file path would make apply try to
+	// read a saved plan. Tests that plan set their own.
+	options.PlanFilePath = ""
+	options.BackendConfig = map[string]interface{}{
+		"bucket":                      config.Bucket,
+		"key":                         config.Key,
+		"region":                      config.Region,
+		"endpoints":                   config.Endpoints,
+		"use_path_style":              config.UsePathStyle,
+		"skip_region_validation":      config.SkipRegionValidation,
+		"skip_requesting_account_id":  config.SkipRequestingAccountID,
+		"skip_credentials_validation": config.SkipCredentialsValidation,
 	}
-	return false
+	options.EnvVars["AWS_ACCESS_KEY_ID"] = "test"
+	options.EnvVars["AWS_SECRET_ACCESS_KEY"] = "test"
+	options.EnvVars["AWS_REGION"] = config.Region
+	if config.UseLockfile != nil {
+		options.BackendConfig["use_lockfile"] = *config.UseLockfile
+	}
+	return options
 }
 
-func containsS3Backend(terraformBlock *hclsyntax.Block) bool {
-	for _, nested := range terraformBlock.Body.Blocks {
-		if nested.Type != "backend" {
-			continue
-		}
-		if len(nested.Labels) == 0 {
-			continue
-		}
-		if isS3BackendBlock(nested) {
-			return true
-		}
-	}
-	return false
+// transientBackendErrors matches the tofu output of a backend request that
+// failed with a 503, which is worth retrying.
+var transientBackendErrors = map[string]string{
+	`(?i)(status ?code: 503|service ?unavailable)`: "S3 backend returned a transient 503",
 }
 
-func isS3BackendBlock(block *hclsyntax.Block) bool {
-	if block.Type != "backend" {
-		return false
+// terraformOptionsWithRetry makes terratest rerun tofu commands that fail with
+// a transient backend error, up to maxRetries times with a short pause.
+func terraformOptionsWithRetry(options *terraform.Options, maxRetries int) *terraform.Options {
+	options.RetryableTerraformErrors = transientBackendErrors
+	options.MaxRetries = maxRetries
+	options.TimeBetweenRetries = 500 * time.Millisecond
+	return options
+}
+
+// failOnViolations reports every error from a backendcheck rule and stops
+// the test if there were any.
+func failOnViolations(t *testing.T, errs []error) {
+	t.Helper()
+
+	for _, err := range errs {
+		t.Error(err)
 	}
-	if len(block.Labels) == 0 {
-		return false
+	if len(errs) > 0 {
+		t.FailNow()
 	}
-	return block.Labels[0] == "s3"
 }
 
-func findTerraformBlock(t *testing.T, body *hclsyntax.Body) *hclsyntax.Block {
+func validateScalewayRequiredFields(t *testing.T, cfg scalewayBackendConfig) {
 	t.Helper()
 
-	for _, blk := range body.Blocks {
-		if blk.Type == "terraform" {
-			return blk
-		}
-	}
-	t.Fatalf("expected terraform block in backend.tf")
-	return nil
+	failOnViolations(t, backendcheck.ScalewayRequiredFields(cfg))
+	assertMapEntry(t, map[string]interface{}{"endpoints": cfg.Endpoints}, "endpoints", "s3",
+		fmt.Sprintf("https://s3.%s.scw.cloud", cfg.Region), "unexpected Scaleway endpoint")
 }
 
-func validateRequiredVersion(t *testing.T, terraformBlock *hclsyntax.Block) {
+// assertBackendKeyConvention fails the test when key strays from the state
+// key layout.
+func assertBackendKeyConvention(t *testing.T, key string) {
 	t.Helper()
 
-	const expectedRequiredVersion = ">= 1.10.7, < 2.0.0"
-	requiredVersionAttr, ok := terraformBlock.Body.Attributes["required_version"]
-	if !ok {
-		t.Fatalf("expected terraform.required_version to be declared in backend.tf")
+	if err := backendcheck.KeyViolation(key); err != nil {
+		t.Fatal(err)
 	}
+}
 
-	requiredVersionVal, diags := requiredVersionAttr.Expr.Value(&hcl.EvalContext{})
-	if diags.HasErrors() {
-		t.Fatalf("evaluate terraform.required_version: %s", diags.Error())
-	}
-	if requiredVersionVal.AsString() != expectedRequiredVersion {
-		t.Fatalf("expected terraform.required_version %q, got %q", expectedRequiredVersion, requiredVersionVal.AsString())
-	}
+func validateScalewayRequiredBooleans(t *testing.T, cfg scalewayBackendConfig) {
+	t.Helper()
+
+	failOnViolations(t, backendcheck.ScalewayRequiredBooleans(cfg))
 }
 
-func findRequiredProvidersBlock(t *testing.T, terraformBlock *hclsyntax.Block) *hclsyntax.Block {
+func validateScalewayForbiddenCredentials(t *testing.T, cfg scalewayBackendConfig) {
 	t.Helper()
 
-	for _, blk := range terraformBlock.Body.Blocks {
-		if blk.Type == "required_providers" {
-			return blk
-		}
-	}
-	t.Fatalf("expected terraform.required_providers block in backend.tf")
-	return nil
+	failOnViolations(t, backendcheck.ScalewayForbiddenCredentials(cfg))
 }
 
-func validateGitHubProvider(t *testing.T, requiredProviders *hclsyntax.Block) {
+func validateAWSForbiddenCredentials(t *testing.T, cfg scalewayBackendConfig) {
 	t.Helper()
 
-	githubProviderAttr, ok := requiredProviders.Body.Attributes["github"]
-	if !ok {
-		t.Fatalf("expected terraform.required_providers.github to be declared in backend.tf")
-	}
+	failOnViolations(t, backendcheck.AWSForbiddenCredentials(cfg))
+}
 
-	githubProviderVal, diags := githubProviderAttr.Expr.Value(&hcl.EvalContext{})
-	if diags.HasErrors() {
-		t.Fatalf("evaluate terraform.required_providers.github: %s", diags.Error())
-	}
-	if !githubProviderVal.Type().IsObjectType() {
-		t.Fatalf("expected terraform.required_providers.github to be an object, got %s", githubProviderVal.Type().FriendlyName())
-	}
+func validateAWSRequiredFields(t *testing.T, cfg scalewayBackendConfig) {
+	t.Helper()
 
-	attrs := githubProviderVal.AsValueMap()
-	versionVal, ok := attrs["version"]
-	if !ok {
-		t.Fatalf("expected terraform.required_providers.github to declare a version constraint")
-	}
+	failOnViolations(t, backendcheck.AWSRequiredFields(cfg))
+}
 
-	const expectedGitHubProviderVersion = "~> 6.3"
-	if versionVal.AsString() != expectedGitHubProviderVersion {
-		t.Fatalf("expected terraform.required_providers.github.version %q, got %q", expectedGitHubProviderVersion, versionVal.AsString())
-	}
+func validateScalewayOptionalSkipFlags(t *testing.T, cfg scalewayBackendConfig) {
+	t.Helper()
+
+	failOnViolations(t, backendcheck.ScalewayOptionalSkipFlags(cfg))
 }
 
-// TestScalewayBackendConfigAssertsNoInlineSecrets guards the committed
-// tfbackend specimen against accidental credential leakage and regression of
-// the documented defaults.
-func TestScalewayBackendConfigAssertsNoInlineSecrets(t *testing.T) {
-	config := loadScalewayBackendConfig(t)
+func validateBackendEncryptionKept(t *testing.T, cfg scalewayBackendConfig) {
+	t.Helper()
 
-	validateScalewayRequiredFields(t, config)
-	validateScalewayRequiredBooleans(t, config)
-	validateScalewayForbiddenCredentials(t, config)
-	validateScalewayOptionalSkipFlags(t, config)
+	failOnViolations(t, backendcheck.EncryptionKept(cfg))
 }
 
-// TestBackendInitAgainstFakeS3 exercises backend init using the Scaleway
-// template against a local S3-compatible server to guard backend wiring.
-func TestBackendInitAgainstFakeS3(t *testing.T) {
-	config := loadScalewayBackendConfig(t)
-	fakeS3, bucket := startFakeS3(t)
-	defer fakeS3.Close()
+func validateBackendProfileOmitted(t *testing.T, cfg scalewayBackendConfig) {
+	t.Helper()
 
-	config.Bucket = bucket
-	config.Key = "behavioural/test/terraform.tfstate"
-	config.Region = "us-east-1"
-	config.Endpoints = map[string]string{"s3": fakeS3.URL}
+	failOnViolations(t, backendcheck.ProfileOmitted(cfg))
+}
 
-	workspace := copyStackToTemp(t, "..")
-	opts := &terraform.Options{
-		TerraformDir:    workspace,
-		NoColor:         true,
-		TerraformBinary: terraformBinary(),
-		BackendConfig: map[string]interface{}{
-			"bucket":                      config.Bucket,
-			"key":                         config.Key,
-			"region":                      config.Region,
-			"endpoints":                   config.Endpoints,
-			"use_path_style":              config.UsePathStyle,
-			"skip_region_validation":      config.SkipRegionValidation,
-			"skip_requesting_account_id":  config.SkipRequestingAccountID,
-			"skip_credentials_validation": config.SkipCredentialsValidation,
-		},
-		EnvVars: map[string]string{
-			"AWS_ACCESS_KEY_ID":     "test",
-			"AWS_SECRET_ACCESS_KEY": "test",
-			"AWS_REGION":            config.Region,
-		},
-	}
+func loadScalewayBackendConfig(t *testing.T) scalewayBackendConfig {
+	t.Helper()
 
-	if _, err := terraform.InitE(t, opts); err != nil {
-		t.Fatalf("tofu init with fake S3 backend: %v", err)
-	}
+	return loadBackendConfig(t, filepath.Join("..", "backend", "scaleway.tfbackend"))
 }
 
-func validateScalewayRequiredFields(t *testing.T, cfg scalewayBackendConfig) {
+// backendSpecimenGlob matches the committed tfbackend specimens, one per
+// provider.
+var backendSpecimenGlob = filepath.Join("..", "backend", "*.tfbackend")
+
+// loadAllBackendConfigs parses every committed tfbackend specimen, keyed by
+// file name without the extension, so repository-wide validators need not
+// name each provider. It fails when there are none.
+func loadAllBackendConfigs(t *testing.T) map[string]hcl.Body {
 	t.Helper()
 
-	if cfg.Bucket != "df12-tfstate" {
-		t.Fatalf("unexpected bucket %q", cfg.Bucket)
-	}
-	if cfg.Key != "estates/test-case/main/terraform.tfstate" {
-		t.Fatalf("unexpected key %q", cfg.Key)
+	specimens, err := filepath.Glob(repoPath(t, backendSpecimenGlob))
+	if err != nil {
+		t.Fatalf("glob backend specimens: %v", err)
 	}
-	if cfg.Region != "fr-par" {
-		t.Fatalf("unexpected region %q", cfg.Region)
+	if len(specimens) == 0 {
+		t.Fatalf("expected at least one tfbackend specimen under backend/")
 	}
 
-	endpoint, exists := cfg.Endpoints["s3"]
-	if !exists || endpoint != "https://s3.fr-par.scw.cloud" {
-		t.Fatalf("unexpected endpoint map %#v", cfg.Endpoints)
+	parser := hclparse.NewParser()
+	configs := make(map[string]hcl.Body, len(specimens))
+	for _, specimen := range specimens {
+		file, diags := parser.ParseHCLFile(specimen)
+		if diags.HasErrors() {
+			t.Fatalf("parse backend config %s: %s", specimen, diags.Error())
+		}
+		configs[strings.TrimSuffix(filepath.Base(specimen), ".tfbackend")] = file.Body
 	}
+	return configs
 }
 
-func validateScalewayRequiredBooleans(t *testing.T, cfg scalewayBackendConfig) {
+// decodeBackendConfig decodes a body from loadAllBackendConfigs into the
+// shared backend struct.
+func decodeBackendConfig(t *testing.T, name string, body hcl.Body) scalewayBackendConfig {
 	t.Helper()
 
-	assertBoolTrue(t, map[string]interface{}{"use_path_style": cfg.UsePathStyle}, "use_path_style", "use_path_style must be true for Scaleway")
-	assertBoolTrue(t, map[string]interface{}{"skip_region_validation": cfg.SkipRegionValidation}, "skip_region_validation", "skip_region_validation must be true to avoid AWS region probes")
-	assertBoolTrue(t, map[string]interface{}{"skip_requesting_account_id": cfg.SkipRequestingAccountID}, "skip_requesting_account_id", "skip_requesting_account_id must prevent AWS-specific API calls")
-	assertBoolTrue(t, map[string]interface{}{"skip_credentials_validation": cfg.SkipCredentialsValidation}, "skip_credentials_validation", "skip_credentials_validation avoids credentials lookups")
+	var config scalewayBackendConfig
+	if diags := gohcl.DecodeBody(body, nil, &config); diags.HasErrors() {
+		t.Fatalf("decode backend config %s: %s", name, diags.Error())
+	}
+	return config
 }
 
-func validateScalewayForbiddenCredentials(t *testing.T, cfg scalewayBackendConfig) {
+// loadBackendConfig decodes any tfbackend file into the shared backend struct.
+func loadBackendConfig(t *testing.T, sourcePath string) scalewayBackendConfig {
 	t.Helper()
 
-	if cfg.UseLockfile != nil && *cfg.UseLockfile {
-		t.Fatalf("use_lockfile should be omitted for Scaleway backends")
-	}
-	if cfg.AccessKey != nil || cfg.SecretKey != nil {
-		t.Fatalf("backend config must not embed credentials")
-	}
-	if cfg.SessionToken != nil {
-		t.Fatalf("backend config must not embed session_token")
-	}
-	if cfg.DynamodbTable != nil {
-		t.Fatalf("backend config should not declare DynamoDB locking")
+	config, err := backendconfig.Load(repoPath(t, sourcePath))
+	if err != nil {
+		t.Fatal(err)
 	}
+	return config
 }
 
-func validateScalewayOptionalSkipFlags(t *testing.T, cfg scalewayBackendConfig) {
+func startFakeS3(t *testing.T) (*httptest.Server, string) {
 	t.Helper()
 
-	if cfg.SkipGetEc2Platforms != nil && !*cfg.SkipGetEc2Platforms {
-		t.Fatalf("skip_get_ec2_platforms should be omitted or true")
-	}
-	if cfg.SkipMetadataApiCheck != nil && !*cfg.SkipMetadataApiCheck {
-		t.Fatalf("skip_metadata_api_check should be omitted or true")
-	}
-	if cfg.SkipOriginAccessValidation != nil && !*cfg.SkipOriginAccessValidation {
-		t.Fatalf("skip_origin_access_validation should be omitted or true")
-	}
+	server, bucket, _ := startFakeS3WithOptions(t, fakeS3Options{})
+	return server, bucket
 }
 
-func loadScalewayBackendConfig(t *testing.T) scalewayBackendConfig {
+// newFakeS3BucketName returns a bucket name with a random suffix, so
+// parallel tests never collide, and checks it against the S3 naming rules.
+func newFakeS3BucketName(t *testing.T) string {
 	t.Helper()
 
-	sourcePath := filepath.Join("..", "backend", "scaleway.tfbackend")
-	data, err := os.ReadFile(sourcePath)
-	if err != nil {
-		t.Fatalf("read scaleway backend config: %v", err)
+	suffix := make([]byte, 8)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatalf("generate fake S3 bucket suffix: %v", err)
 	}
+	bucket := "fake-s3-" + hex.EncodeToString(suffix)
+	if err := validateBucketName(bucket); err != nil {
+		t.Fatalf("generated fake S3 bucket name: %v", err)
+	}
+	return bucket
+}
 
-	var config scalewayBackendConfig
-	if err := hclsimple.Decode("scaleway.hcl", data, nil, &config); err != nil {
-		t.Fatalf("decode scaleway backend config: %v", err)
+// bucketNamePattern covers the S3 character and boundary rules: lower-case
+// letters, digits, dots, and hyphens, starting and ending with a letter or
+// digit.
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*[a-z0-9]$`)
+
+// validateBucketName applies the S3 bucket naming rules the fake's names
+// must satisfy to be usable against real S3-compatible stores.
+func validateBucketName(name string) error {
+	switch {
+	case len(name) < 3 || len(name) > 63:
+		return fmt.Errorf("bucket name %q must be 3-63 characters long", name)
+	case !bucketNamePattern.MatchString(name):
+		return fmt.Errorf("bucket name %q must use lower-case letters, digits, dots, and hyphens, and start and end with a letter or digit", name)
+	case strings.Contains(name, ".."):
+		return fmt.Errorf("bucket name %q must not contain consecutive dots", name)
 	}
-	return config
+	return nil
 }
 
-func startFakeS3(t *testing.T) (*httptest.Server, string) {
+// startFakeS3WithOptions starts an in-memory S3 server, creates a uniquely
+// named bucket, and uploads any seed objects. The client is returned so tests
+// can inspect what tofu wrote to the bucket.
+func startFakeS3WithOptions(t *testing.T, opts fakeS3Options) (*httptest.Server, string, *s3.S3) {
 	t.Helper()
 
 	memBackend := s3mem.New()
 	fake := gofakes3.New(memBackend)
-	server := httptest.NewServer(fake.Server())
+	bucket := newFakeS3BucketName(t)
+	var handler http.Handler = fake.Server()
+	if opts.transientFailures != nil {
+		handler = opts.transientFailures.wrap(handler, bucket)
+	}
+	server := httptest.NewServer(handler)
 
 	awsConfig := &aws.Config{
 		Region:           aws.String("us-east-1"),
@@ -465,93 +3710,54 @@ func startFakeS3(t *testing.T) (*httptest.Server, string) {
 
 	sess := session.Must(session.NewSession(awsConfig))
 	client := s3.New(sess)
-	bucket := strings.ReplaceAll("fake-s3-"+time.Now().UTC().Format("150405.000000000"), ".", "-")
 
 	if _, err := client.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
 		t.Fatalf("create bucket on fake S3: %v", err)
 	}
 
-	return server, bucket
-}
-
-func copyStackToTemp(t *testing.T, src string) string {
-	t.Helper()
-
-	dst := t.TempDir()
-	ctx := copyContext{src: src, dst: dst}
-	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
-		return copyStackEntry(ctx, path, d, err)
-	})
-	if err != nil {
-		t.Fatalf("copy stack to temp: %v", err)
-	}
-
-	return dst
-}
-
-func copyStackEntry(ctx copyContext, path string, d fs.DirEntry, err error) error {
-	if err != nil {
-		return err
-	}
-
-	rel, err := filepath.Rel(ctx.src, path)
-	if err != nil {
-		return err
-	}
-
-	if rel == "." {
-		return nil
-	}
-
-	// Skip terraform artifacts and VCS metadata
-	if shouldSkipPath(rel) {
-		if d.IsDir() {
-			return filepath.SkipDir
+	for key, body := range opts.seedObjects {
+		_, err := client.PutObject(&s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   bytes.NewReader(body),
+		})
+		if err != nil {
+			t.Fatalf("seed object %s on fake S3: %v", key, err)
 		}
-		return nil
 	}
 
-	target := filepath.Join(ctx.dst, rel)
-	if d.IsDir() {
-		return os.MkdirAll(target, 0o755)
+	if opts.transientFailures != nil {
+		opts.transientFailures.arm()
 	}
-
-	return copyFile(path, target)
+	return server, bucket, client
 }
 
-// shouldSkipPath returns true if the relative path represents a terraform
-// artefact or VCS metadata that should be excluded when copying a stack.
-func shouldSkipPath(rel string) bool {
-	if rel == ".terraform" {
-		return true
-	}
-	if strings.HasPrefix(rel, ".git") {
-		return true
+// workspaceStateKey returns where the S3 backend stores state for workspace
+// under key, using the backend's default workspace_key_prefix of "env:".
+func workspaceStateKey(key, workspace string) string {
+	if workspace == "default" {
+		return key
 	}
-	if rel == ".terraform.lock.hcl" {
-		return true
-	}
-	return false
+	return "env:/" + workspace + "/" + key
 }
-func copyFile(src, dst string) error {
-	in, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer in.Close()
 
-	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
-		return err
-	}
+// readFakeS3Object fetches an object body from the fake bucket, failing the
+// test when the object is missing.
+func readFakeS3Object(t *testing.T, client *s3.S3, bucket, key string) []byte {
+	t.Helper()
 
-	out, err := os.Create(dst)
+	object, err := client.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
 	if err != nil {
-		return err
+		t.Fatalf("fetch %s from fake S3: %v", key, err)
 	}
-	defer out.Close()
+	defer object.Body.Close()
 
-	if _, err := io.Copy(out, in); err != nil {
-		return err
+	body, err := io.ReadAll(object.Body)
+	if err != nil {
+		t.Fatalf("read %s from fake S3: %v", key, err)
 	}
-	return out.Sync()
+	return body
 }
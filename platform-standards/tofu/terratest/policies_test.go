@@ -0,0 +1,146 @@
+package terratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// policyDir holds the Rego policies evaluated against fixture plans.
+var policyDir = filepath.Join("..", "policies")
+
+// conftestResult mirrors one entry of `conftest test --output json`.
+type conftestResult struct {
+	Filename  string `json:"filename"`
+	Namespace string `json:"namespace"`
+	Failures  []struct {
+		Msg string `json:"msg"`
+	} `json:"failures"`
+}
+
+// conftestBinary returns the conftest executable, honouring
+// CONCORDAT_CONFTEST for non-standard installs.
+func conftestBinary() string {
+	if binary := strings.TrimSpace(os.Getenv("CONCORDAT_CONFTEST")); binary != "" {
+		return binary
+	}
+	return "conftest"
+}
+
+// policyDenials runs every namespace in policyDir against planJSON and
+// returns the sorted deny messages. It skips the test when conftest is not
+// installed.
+func policyDenials(t *testing.T, planJSON []byte, policyDir string) []string {
+	t.Helper()
+
+	binary := conftestBinary()
+	if _, err := exec.LookPath(binary); err != nil {
+		t.Skipf("conftest binary %q not found; install conftest or set CONCORDAT_CONFTEST: %v", binary, err)
+	}
+
+	planPath := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(planPath, planJSON, 0o600); err != nil {
+		t.Fatalf("write plan JSON: %v", err)
+	}
+
+	// conftest exits non-zero when a policy denies, so the JSON report, not
+	// the exit status, decides whether the run worked.
+	cmd := exec.Command(binary, "test", "--all-namespaces", "--no-color", "--output", "json", "--policy", repoPath(t, policyDir), planPath)
+	output, runErr := cmd.Output()
+
+	var results []conftestResult
+	if err := json.Unmarshal(output, &results); err != nil {
+		t.Fatalf("parse conftest output (run error: %v): %v\n%s", runErr, err, output)
+	}
+
+	var denials []string
+	for _, result := range results {
+		for _, failure := range result.Failures {
+			denials = append(denials, fmt.Sprintf("%s: %s", result.Namespace, failure.Msg))
+		}
+	}
+	sort.Strings(denials)
+	return denials
+}
+
+// evalPolicies fails the test with one error per Rego denial for planJSON.
+func evalPolicies(t *testing.T, planJSON []byte, policyDir string) {
+	t.Helper()
+
+	for _, denial := range policyDenials(t, planJSON, policyDir) {
+		t.Errorf("policy denied plan: %s", denial)
+	}
+}
+
+// writeStubConftest writes a conftest stand-in that prints report and exits
+// with status.
+func writeStubConftest(t *testing.T, report string, status int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "conftest")
+	script := fmt.Sprintf("#!/bin/sh\ncat <<'JSON'\n%s\nJSON\nexit %d\n", report, status)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write stub conftest %s: %v", path, err)
+	}
+	return path
+}
+
+// TestPolicyDenialsCollectsFailures checks denials from every namespace are
+// gathered even though conftest exits non-zero.
+func TestPolicyDenialsCollectsFailures(t *testing.T) {
+	report := `[
+  {"filename": "plan.json", "namespace": "platform_standards.team", "successes": 3, "failures": [{"msg": "team repo binding x uses disallowed permission admin"}]},
+  {"filename": "plan.json", "namespace": "platform_standards.environment", "successes": 0, "failures": [{"msg": "environment y waits 1 minutes; at least 5 are required"}]},
+  {"filename": "plan.json", "namespace": "platform_standards.branch", "successes": 2}
+]`
+	t.Setenv("CONCORDAT_CONFTEST", writeStubConftest(t, report, 1))
+
+	got := policyDenials(t, []byte(`{}`), policyDir)
+	want := []string{
+		"platform_standards.environment: environment y waits 1 minutes; at least 5 are required",
+		"platform_standards.team: team repo binding x uses disallowed permission admin",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected denials:\nwant %q\ngot  %q", want, got)
+	}
+}
+
+// TestEnvironmentModuleSatisfiesPolicies evaluates the Rego policies against
+// the compliant environment fixture.
+func TestEnvironmentModuleSatisfiesPolicies(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "environment", "tests", "fixture")
+
+		planJSON := terraform.InitAndPlanAndShow(t, options)
+		evalPolicies(t, []byte(planJSON), policyDir)
+	})
+}
+
+// TestEnvironmentModulePolicyRejectsShortWait ensures a wait timer the module
+// accepts is still denied by the environment policy.
+func TestEnvironmentModulePolicyRejectsShortWait(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		options := terraformOptions(t, binary, "..", "modules", "environment", "tests", "fixture_short_wait")
+
+		planJSON := terraform.InitAndPlanAndShow(t, options)
+		want := "platform_standards.environment: environment module.environment.github_repository_environment.this waits 1 minutes; at least 5 are required"
+		denials := policyDenials(t, []byte(planJSON), policyDir)
+		for _, denial := range denials {
+			if denial == want {
+				return
+			}
+		}
+		t.Fatalf("expected denial %q, got %q", want, denials)
+	})
+}
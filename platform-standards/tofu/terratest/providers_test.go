@@ -0,0 +1,197 @@
+package terratest
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// TestModulesDeclareProviderRequirements checks every module receives its
+// providers from the root: none embeds a provider block, and any
+// configuration alias it declares is one the root passes in.
+func TestModulesDeclareProviderRequirements(t *testing.T) {
+	passed := rootPassedProviders(t)
+	for _, dir := range moduleDirs(t) {
+		name := filepath.Base(dir)
+		t.Run(name, func(t *testing.T) {
+			for _, err := range moduleProviderViolations(parseModuleFiles(t, dir), passed[name]) {
+				t.Errorf("module %s: %v", name, err)
+			}
+		})
+	}
+}
+
+// TestModuleProviderViolationsFlagsEmbeddedProviders checks the specimen's
+// provider block and unpassed alias are both reported, and that passing the
+// alias from the root clears the alias finding.
+func TestModuleProviderViolationsFlagsEmbeddedProviders(t *testing.T) {
+	files := parseModuleFiles(t, filepath.Join("testdata", "provider_embedded"))
+
+	errs := moduleProviderViolations(files, nil)
+	if len(errs) != 2 {
+		t.Fatalf("expected two findings, got %v", errs)
+	}
+	if !strings.Contains(errs[0].Error(), "github.other") {
+		t.Errorf("expected the unpassed alias to be named, got %v", errs[0])
+	}
+	if !strings.Contains(errs[1].Error(), `embeds provider "github"`) || !strings.Contains(errs[1].Error(), "main.tofu:15") {
+		t.Errorf("expected the embedded provider block to be located, got %v", errs[1])
+	}
+
+	errs = moduleProviderViolations(files, map[string]bool{"github.other": true})
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "embeds provider") {
+		t.Fatalf("expected only the embedded provider once the alias is passed, got %v", errs)
+	}
+}
+
+// TestRootPassedProvidersReadsProvidersMap checks the providers map of a
+// root module call is keyed by module name and child address.
+func TestRootPassedProvidersReadsProvidersMap(t *testing.T) {
+	body := parseInlineHCL(t, `
+module "team" {
+  source = "./modules/team"
+  providers = {
+    github.other = github.platform
+  }
+}
+`)
+	passed, err := modulePassedProviders([]hclFile{{path: "main.tofu", body: body}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !passed["team"]["github.other"] || len(passed["team"]) != 1 {
+		t.Fatalf("expected team to receive github.other, got %v", passed)
+	}
+}
+
+// rootPassedProviders maps each module the root calls to the provider
+// addresses its module block passes in.
+func rootPassedProviders(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+
+	passed, err := modulePassedProviders(parseModuleFiles(t, ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return passed
+}
+
+func modulePassedProviders(files []hclFile) (map[string]map[string]bool, error) {
+	passed := map[string]map[string]bool{}
+	for _, file := range files {
+		for _, block := range file.body.Blocks {
+			if block.Type != "module" {
+				continue
+			}
+			sourceAttr, ok := block.Body.Attributes["source"]
+			if !ok {
+				continue
+			}
+			source, diags := sourceAttr.Expr.Value(nil)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("%s: evaluate module source: %s", file.path, diags.Error())
+			}
+			name := filepath.Base(source.AsString())
+			if passed[name] == nil {
+				passed[name] = map[string]bool{}
+			}
+
+			providersAttr, ok := block.Body.Attributes["providers"]
+			if !ok {
+				continue
+			}
+			pairs, diags := hcl.ExprMap(providersAttr.Expr)
+			if diags.HasErrors() {
+				return nil, fmt.Errorf("%s: read providers map: %s", file.path, diags.Error())
+			}
+			for _, pair := range pairs {
+				address, err := providerAddress(pair.Key)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %v", file.path, err)
+				}
+				passed[name][address] = true
+			}
+		}
+	}
+	return passed, nil
+}
+
+// moduleProviderViolations reports provider blocks embedded in a module and
+// configuration_aliases entries that are not in passed.
+func moduleProviderViolations(files []hclFile, passed map[string]bool) []error {
+	var errs []error
+	for _, file := range files {
+		for _, block := range file.body.Blocks {
+			switch block.Type {
+			case "terraform":
+				errs = append(errs, unpassedAliasViolations(block, passed)...)
+			case "provider":
+				errs = append(errs, fmt.Errorf("embeds provider %q at %s; modules must receive providers from the root", strings.Join(block.Labels, "."), block.DefRange()))
+			}
+		}
+	}
+	return errs
+}
+
+func unpassedAliasViolations(terraformBlock *hclsyntax.Block, passed map[string]bool) []error {
+	requiredProviders := findBlock(terraformBlock.Body, "required_providers")
+	if requiredProviders == nil {
+		return nil
+	}
+
+	names := make([]string, 0, len(requiredProviders.Body.Attributes))
+	for name := range requiredProviders.Body.Attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		attr := requiredProviders.Body.Attributes[name]
+		pairs, diags := hcl.ExprMap(attr.Expr)
+		if diags.HasErrors() {
+			continue
+		}
+		for _, pair := range pairs {
+			if hcl.ExprAsKeyword(pair.Key) != "configuration_aliases" {
+				continue
+			}
+			aliases, diags := hcl.ExprList(pair.Value)
+			if diags.HasErrors() {
+				errs = append(errs, fmt.Errorf("required_providers.%s.configuration_aliases at %s must be a list of provider addresses", attr.Name, pair.Value.Range()))
+				continue
+			}
+			for _, alias := range aliases {
+				address, err := providerAddress(alias)
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if !passed[address] {
+					errs = append(errs, fmt.Errorf("required_providers.%s declares configuration alias %s at %s, but the root does not pass it", attr.Name, address, alias.Range()))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// providerAddress renders a provider reference such as github.other.
+func providerAddress(expr hcl.Expression) (string, error) {
+	traversal, diags := hcl.AbsTraversalForExpr(expr)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("expected a provider address at %s", expr.Range())
+	}
+	parts := []string{traversal.RootName()}
+	for _, step := range traversal[1:] {
+		if attr, ok := step.(hcl.TraverseAttr); ok {
+			parts = append(parts, attr.Name)
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
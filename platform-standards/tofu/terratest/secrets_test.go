@@ -0,0 +1,234 @@
+package terratest
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// secretAttributeNames lists attributes that must never carry a literal
+// credential in committed HCL.
+var secretAttributeNames = map[string]bool{
+	"access_key":    true,
+	"secret_key":    true,
+	"session_token": true,
+	"token":         true,
+	"client_secret": true,
+	"github_token":  true,
+}
+
+// placeholderSecrets are literal values fixtures use where a provider insists
+// on a credential but never reaches a real API.
+var placeholderSecrets = map[string]bool{
+	"placeholder": true,
+}
+
+// inlineSecretFindings reports every secret-named attribute in body, at any
+// nesting depth, that is assigned a non-empty literal.
+func inlineSecretFindings(body *hclsyntax.Body) []string {
+	var findings []string
+	for name, attr := range body.Attributes {
+		if secretAttributeNames[name] && isInlineSecret(attr.Expr) {
+			findings = append(findings, fmt.Sprintf("%s (%s)", name, attr.NameRange))
+		}
+		findings = append(findings, objectSecretFindings(attr.Expr)...)
+	}
+	for _, blk := range body.Blocks {
+		findings = append(findings, inlineSecretFindings(blk.Body)...)
+	}
+	sort.Strings(findings)
+	return findings
+}
+
+// objectSecretFindings covers secrets nested in object constructors, such as
+// credentials maps passed to a provider.
+func objectSecretFindings(expr hclsyntax.Expression) []string {
+	object, ok := expr.(*hclsyntax.ObjectConsExpr)
+	if !ok {
+		return nil
+	}
+
+	var findings []string
+	for _, item := range object.Items {
+		key, diags := item.KeyExpr.Value(nil)
+		if !diags.HasErrors() && key.Type() == cty.String && secretAttributeNames[key.AsString()] && isInlineSecret(item.ValueExpr) {
+			findings = append(findings, fmt.Sprintf("%s (%s)", key.AsString(), item.KeyExpr.Range()))
+		}
+		findings = append(findings, objectSecretFindings(item.ValueExpr)...)
+	}
+	return findings
+}
+
+// isInlineSecret reports whether expr is a non-empty string literal. Variable
+// references and function calls, such as var.github_token, are fine because
+// the value is supplied at run time.
+func isInlineSecret(expr hclsyntax.Expression) bool {
+	if len(expr.Variables()) > 0 {
+		return false
+	}
+	if _, isCall := expr.(*hclsyntax.FunctionCallExpr); isCall {
+		return false
+	}
+	value, diags := expr.Value(&hcl.EvalContext{})
+	if diags.HasErrors() || value.IsNull() || !value.IsKnown() || value.Type() != cty.String {
+		return false
+	}
+	literal := strings.TrimSpace(value.AsString())
+	return literal != "" && !placeholderSecrets[literal]
+}
+
+// TestNoInlineSecretsAnywhere extends the backend specimen's credential guard
+// to every HCL file in the stack, modules, and test fixtures.
+func TestNoInlineSecretsAnywhere(t *testing.T) {
+	parser := hclparse.NewParser()
+	err := filepath.WalkDir(repoPath(t, ".."), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".terraform" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".tf", ".tofu", ".tfbackend":
+		default:
+			return nil
+		}
+
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			t.Errorf("parse %s: %s", path, diags.Error())
+			return nil
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			t.Errorf("%s unexpected body type %T", path, file.Body)
+			return nil
+		}
+		for _, finding := range inlineSecretFindings(body) {
+			t.Errorf("%s assigns a literal secret to %s", path, finding)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk HCL files: %v", err)
+	}
+}
+
+// TestInlineSecretFindingsFlagsLiterals checks literals are flagged while
+// variable references, function calls, placeholders, and empty strings pass.
+func TestInlineSecretFindingsFlagsLiterals(t *testing.T) {
+	body := parseInlineHCL(t, `
+provider "github" {
+  token = "ghp_literal"
+}
+
+provider "github" {
+  alias = "from_var"
+  token = var.github_token
+}
+
+provider "github" {
+  alias = "from_call"
+  token = file("token.txt")
+}
+
+provider "github" {
+  alias = "fixture"
+  token = "placeholder"
+}
+
+locals {
+  empty       = { secret_key = "" }
+  credentials = { access_key = "AKIAEXAMPLE" }
+}
+`)
+
+	findings := inlineSecretFindings(body)
+	if len(findings) != 2 {
+		t.Fatalf("expected two findings, got %q", findings)
+	}
+	if !strings.HasPrefix(findings[0], "access_key") || !strings.HasPrefix(findings[1], "token") {
+		t.Fatalf("expected access_key and token findings, got %q", findings)
+	}
+}
+
+// TestProviderUsesAppAuth requires every github provider outside test
+// fixtures to authenticate as a GitHub App rather than with a token.
+func TestProviderUsesAppAuth(t *testing.T) {
+	found := 0
+	for _, file := range stackConfigFiles(t) {
+		for _, block := range file.body.Blocks {
+			if block.Type != "provider" || len(block.Labels) != 1 || block.Labels[0] != "github" {
+				continue
+			}
+			found++
+			if err := githubProviderAuthViolation(block); err != nil {
+				t.Errorf("%s: %v", file.path, err)
+			}
+		}
+	}
+	if found == 0 {
+		t.Fatalf("expected the stack to declare a github provider")
+	}
+}
+
+// TestProviderAuthViolationFlagsTokens checks the specimen's token is named
+// in the failure and that an app_auth provider passes.
+func TestProviderAuthViolationFlagsTokens(t *testing.T) {
+	files := parseModuleFiles(t, filepath.Join("testdata", "provider_token"))
+	block := findBlock(files[0].body, "provider", "github")
+	if block == nil {
+		t.Fatalf("expected the specimen to declare a github provider")
+	}
+	err := githubProviderAuthViolation(block)
+	if err == nil || !strings.Contains(err.Error(), "token") {
+		t.Fatalf("expected the token attribute to be flagged, got %v", err)
+	}
+
+	appAuth := findBlock(parseInlineHCL(t, `
+provider "github" {
+  owner = "platform"
+
+  app_auth {}
+}
+`), "provider", "github")
+	if err := githubProviderAuthViolation(appAuth); err != nil {
+		t.Fatalf("expected app_auth provider to pass, got %v", err)
+	}
+}
+
+// githubProviderAuthViolation reports a github provider block that sets a
+// token or lacks an app_auth block.
+func githubProviderAuthViolation(block *hclsyntax.Block) error {
+	if attr, ok := block.Body.Attributes["token"]; ok {
+		return fmt.Errorf("provider \"github\" sets token at %s; authenticate with an app_auth block instead of a personal access token", attr.NameRange)
+	}
+	if findBlock(block.Body, "app_auth") == nil {
+		return fmt.Errorf("provider \"github\" at %s has no app_auth block; configure GitHub App authentication", block.DefRange())
+	}
+	return nil
+}
+
+// stackConfigFiles parses the stack's own configuration: the root and module
+// files, leaving out module test fixtures and this suite's specimens, which
+// authenticate with placeholders.
+func stackConfigFiles(t *testing.T) []hclFile {
+	t.Helper()
+
+	files := parseModuleFiles(t, "..")
+	for _, dir := range moduleDirs(t) {
+		files = append(files, parseModuleFiles(t, dir)...)
+	}
+	return files
+}
@@ -0,0 +1,9 @@
+# Specimen for TestBackendInlineConfigFlagsBucket: a shared stack that
+# hardcodes an estate's bucket instead of taking it from a tfbackend file.
+
+terraform {
+  backend "s3" {
+    bucket  = "df12-tfstate"
+    encrypt = true
+  }
+}
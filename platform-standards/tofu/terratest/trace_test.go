@@ -0,0 +1,199 @@
+package terratest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+)
+
+// traceEnv opts into a one-line summary per tofu command in the test log.
+// The per-test trace file is written either way.
+const traceEnv = "CONCORDAT_TRACE"
+
+// tofuTrace records each tofu invocation a test makes as a JSON line with
+// its start time, duration, and exit status.
+type tofuTrace struct {
+	path   string
+	logger *slog.Logger
+	// echo, when set, receives a one-line summary per command.
+	echo func(format string, args ...interface{})
+}
+
+// traces holds one tofuTrace per test so repeated plans in a test append to
+// the same file.
+var traces sync.Map
+
+// traceFor returns the trace for t, creating its file under t.TempDir on
+// first use. The file name is derived from the test name.
+func traceFor(t *testing.T) *tofuTrace {
+	t.Helper()
+
+	if existing, ok := traces.Load(t); ok {
+		return existing.(*tofuTrace)
+	}
+
+	path := filepath.Join(t.TempDir(), testFileName(t)+".trace.jsonl")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create tofu trace %s: %v", path, err)
+	}
+
+	trace := &tofuTrace{
+		path:   path,
+		logger: slog.New(slog.NewJSONHandler(file, nil)).With("test", t.Name()),
+	}
+	if os.Getenv(traceEnv) == "1" {
+		trace.echo = t.Logf
+	}
+	traces.Store(t, trace)
+	t.Cleanup(func() {
+		traces.Delete(t)
+		_ = file.Close()
+	})
+	return trace
+}
+
+// testFileName turns t.Name() into a single path element, flattening the
+// slashes subtests add.
+func testFileName(t *testing.T) string {
+	return strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+}
+
+// run times fn as the named tofu command and records the outcome.
+func (trace *tofuTrace) run(command string, fn func() error) error {
+	start := time.Now()
+	err := fn()
+	elapsed := time.Since(start)
+
+	status := "ok"
+	attrs := []any{
+		"command", command,
+		"start", start.UTC(),
+		"duration_ms", elapsed.Milliseconds(),
+	}
+	if err != nil {
+		status = "error"
+		attrs = append(attrs, "error", err.Error())
+	}
+	attrs = append(attrs, "status", status)
+	trace.logger.Info("tofu", attrs...)
+
+	if trace.echo != nil {
+		trace.echo("tofu %s: %s in %s", command, status, elapsed.Round(time.Millisecond))
+	}
+	return err
+}
+
+// tracedPlan is InitAndPlanAndShowWithStruct with each tofu command recorded
+// in the test's trace file.
+func tracedPlan(t *testing.T, options *terraform.Options) *terraform.PlanStruct {
+	t.Helper()
+
+	trace := traceFor(t)
+	var planJSON string
+	steps := []struct {
+		command string
+		fn      func() error
+	}{
+		{"init", func() error { _, err := terraform.InitE(t, options); return err }},
+		{"plan", func() error { _, err := terraform.PlanE(t, options); return err }},
+		{"show", func() (err error) { planJSON, err = terraform.ShowE(t, options); return err }},
+	}
+	for _, step := range steps {
+		if err := trace.run(step.command, step.fn); err != nil {
+			t.Fatalf("tofu %s: %v", step.command, err)
+		}
+	}
+
+	plan, err := terraform.ParsePlanJSON(planJSON)
+	if err != nil {
+		t.Fatalf("parse plan JSON: %v", err)
+	}
+	return plan
+}
+
+// traceRecord is the subset of a trace line the tests inspect.
+type traceRecord struct {
+	Test    string `json:"test"`
+	Command string `json:"command"`
+	Status  string `json:"status"`
+}
+
+// readTrace decodes every line of the trace file at path.
+func readTrace(t *testing.T, path string) []traceRecord {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open trace: %v", err)
+	}
+	defer file.Close()
+
+	var records []traceRecord
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record traceRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("decode trace line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("read trace: %v", err)
+	}
+	return records
+}
+
+// TestTofuTraceRecordsCommands checks each traced command lands in the
+// per-test trace file with its status, and that echo stays off by default.
+func TestTofuTraceRecordsCommands(t *testing.T) {
+	trace := traceFor(t)
+	if trace.echo != nil && os.Getenv(traceEnv) != "1" {
+		t.Fatalf("expected echo to be off unless %s=1", traceEnv)
+	}
+
+	var echoed []string
+	trace.echo = func(format string, args ...interface{}) {
+		echoed = append(echoed, fmt.Sprintf(format, args...))
+	}
+	_ = trace.run("init", func() error { return nil })
+	_ = trace.run("plan", func() error { return nil })
+	if err := trace.run("show", func() error { return errors.New("exit status 1") }); err == nil {
+		t.Fatalf("expected run to return the command error")
+	}
+
+	if traceFor(t) != trace {
+		t.Fatalf("expected repeated calls to reuse the test's trace")
+	}
+	if !strings.HasSuffix(trace.path, "TestTofuTraceRecordsCommands.trace.jsonl") {
+		t.Fatalf("expected trace file to be keyed by test name, got %s", trace.path)
+	}
+
+	records := readTrace(t, trace.path)
+	want := []traceRecord{
+		{Test: t.Name(), Command: "init", Status: "ok"},
+		{Test: t.Name(), Command: "plan", Status: "ok"},
+		{Test: t.Name(), Command: "show", Status: "error"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("expected %d trace records, got %+v", len(want), records)
+	}
+	for i := range want {
+		if records[i] != want[i] {
+			t.Errorf("record %d: expected %+v, got %+v", i, want[i], records[i])
+		}
+	}
+	if len(echoed) != 3 || !strings.HasPrefix(echoed[2], "tofu show: error in ") {
+		t.Fatalf("expected one summary line per command, got %q", echoed)
+	}
+}
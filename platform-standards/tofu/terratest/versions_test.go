@@ -0,0 +1,180 @@
+package terratest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+// tofuMatrix holds the OpenTofu binaries named by CONCORDAT_TOFU_VERSIONS.
+// When it is empty the plan tests run once against terraformBinary().
+var tofuMatrix []string
+
+// parseTofuMatrix splits a comma-separated CONCORDAT_TOFU_VERSIONS value.
+// Entries that parse as a version, such as "1.10.7" or "v1.11.0", name a
+// "tofu-<version>" binary on PATH; anything else is used as a binary path.
+func parseTofuMatrix(spec string) []string {
+	var binaries []string
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, err := version.NewVersion(entry); err == nil {
+			entry = "tofu-" + strings.TrimPrefix(entry, "v")
+		}
+		binaries = append(binaries, entry)
+	}
+	return binaries
+}
+
+// resolveTofuMatrix checks every binary in spec exists and reports a version
+// the stack's required_version accepts, so an unsupported binary fails the
+// suite before any plan runs.
+func resolveTofuMatrix(spec, backendPath string) ([]string, error) {
+	binaries := parseTofuMatrix(spec)
+	if len(binaries) == 0 {
+		return nil, nil
+	}
+
+	constraints, err := requiredTofuConstraints(backendPath)
+	if err != nil {
+		return nil, err
+	}
+	for _, binary := range binaries {
+		if _, err := exec.LookPath(binary); err != nil {
+			return nil, fmt.Errorf("CONCORDAT_TOFU_VERSIONS binary %q not found: %w", binary, err)
+		}
+		v, err := tofuVersion(binary)
+		if err != nil {
+			return nil, err
+		}
+		if !constraints.Check(v) {
+			return nil, fmt.Errorf("CONCORDAT_TOFU_VERSIONS binary %q reports version %s outside required_version %q", binary, v, constraints)
+		}
+	}
+	return binaries, nil
+}
+
+// tofuVersion asks binary for its version using the JSON output both OpenTofu
+// and Terraform support.
+func tofuVersion(binary string) (*version.Version, error) {
+	out, err := exec.Command(binary, "version", "-json").Output()
+	if err != nil {
+		return nil, fmt.Errorf("run %s version: %w", binary, err)
+	}
+
+	var report struct {
+		TerraformVersion string `json:"terraform_version"`
+	}
+	if err := json.Unmarshal(out, &report); err != nil {
+		return nil, fmt.Errorf("decode %s version output: %w", binary, err)
+	}
+	v, err := version.NewVersion(report.TerraformVersion)
+	if err != nil {
+		return nil, fmt.Errorf("parse %s version %q: %w", binary, report.TerraformVersion, err)
+	}
+	return v, nil
+}
+
+// requiredTofuConstraints reads terraform.required_version from the stack's
+// backend file.
+func requiredTofuConstraints(backendPath string) (version.Constraints, error) {
+	parser := hclparse.NewParser()
+	file, diag := parser.ParseHCLFile(backendPath)
+	if diag.HasErrors() {
+		return nil, fmt.Errorf("parse %s: %s", backendPath, diag.Error())
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("%s: unexpected body type %T", backendPath, file.Body)
+	}
+
+	blk := findBlock(body, "terraform")
+	if blk == nil {
+		return nil, fmt.Errorf("%s does not declare a terraform block", backendPath)
+	}
+	attr, ok := blk.Body.Attributes["required_version"]
+	if !ok {
+		return nil, fmt.Errorf("%s does not declare terraform.required_version", backendPath)
+	}
+	value, diags := attr.Expr.Value(&hcl.EvalContext{})
+	if diags.HasErrors() {
+		return nil, fmt.Errorf("evaluate required_version in %s: %s", backendPath, diags.Error())
+	}
+	constraints, err := version.NewConstraint(value.AsString())
+	if err != nil {
+		return nil, fmt.Errorf("parse required_version in %s: %w", backendPath, err)
+	}
+	return constraints, nil
+}
+
+// forEachTofu runs fn once per binary in the version matrix, as a subtest
+// named after the binary. Without a matrix it calls fn directly with
+// terraformBinary() so test names stay unchanged.
+func forEachTofu(t *testing.T, fn func(t *testing.T, binary string)) {
+	t.Helper()
+
+	if len(tofuMatrix) == 0 {
+		fn(t, terraformBinary())
+		return
+	}
+	for _, binary := range tofuMatrix {
+		t.Run(filepath.Base(binary), func(t *testing.T) {
+			t.Parallel()
+
+			fn(t, binary)
+		})
+	}
+}
+
+// TestParseTofuMatrix covers the version-tag and path forms accepted by
+// CONCORDAT_TOFU_VERSIONS.
+func TestParseTofuMatrix(t *testing.T) {
+	got := parseTofuMatrix(" 1.10.7, v1.11.0 ,, /opt/tofu/bin/tofu,tofu")
+	want := []string{"tofu-1.10.7", "tofu-1.11.0", "/opt/tofu/bin/tofu", "tofu"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("expected matrix %q, got %q", want, got)
+	}
+}
+
+// TestResolveTofuMatrixRejectsUnsupportedVersion feeds a stub binary that
+// reports a version below required_version and expects the matrix to fail.
+func TestResolveTofuMatrixRejectsUnsupportedVersion(t *testing.T) {
+	backendPath := repoPath(t, "..", "backend.tf")
+	supported := writeStubTofu(t, "supported", "1.10.7")
+	if _, err := resolveTofuMatrix(supported, backendPath); err != nil {
+		t.Fatalf("expected %s to satisfy required_version: %v", supported, err)
+	}
+
+	unsupported := writeStubTofu(t, "unsupported", "1.9.0")
+	_, err := resolveTofuMatrix(supported+","+unsupported, backendPath)
+	if err == nil {
+		t.Fatalf("expected %s to be rejected by required_version", unsupported)
+	}
+	if !strings.Contains(err.Error(), unsupported) {
+		t.Fatalf("expected error to name %s, got %q", unsupported, err)
+	}
+}
+
+// writeStubTofu writes an executable that answers "version -json" with the
+// given version.
+func writeStubTofu(t *testing.T, name, reported string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	script := fmt.Sprintf("#!/bin/sh\necho '{\"terraform_version\":\"%s\"}'\n", reported)
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatalf("write stub tofu %s: %v", path, err)
+	}
+	return path
+}
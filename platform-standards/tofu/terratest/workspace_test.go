@@ -0,0 +1,646 @@
+package terratest
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// defaultCopySkipPatterns keeps tofu artefacts, VCS metadata, and local state
+// out of copied workspaces. Patterns follow .gitignore conventions: a pattern
+// containing "/" matches the path relative to the copy root, anything else
+// matches a file or directory name at any depth.
+var defaultCopySkipPatterns = []string{
+	".terraform",
+	"/.git*",
+	"/.terraform.lock.hcl",
+	"*.tfstate",
+	"crash.log",
+}
+
+type copyContext struct {
+	src  string
+	dst  string
+	skip []string
+	logf func(format string, args ...interface{})
+}
+
+// copyStackToTemp copies src into a fresh temporary directory, skipping paths
+// that match skip, or defaultCopySkipPatterns when no patterns are given.
+// Local modules the stack references from outside src are copied too, at the
+// same relative position, so the returned workspace inits without rewriting
+// any source paths.
+func copyStackToTemp(t *testing.T, src string, skip ...string) string {
+	t.Helper()
+
+	if len(skip) == 0 {
+		skip = defaultCopySkipPatterns
+	}
+
+	absSrc, err := fixturePath(src)
+	if err != nil {
+		t.Fatalf("resolve stack %s: %v", src, err)
+	}
+	external, err := externalModuleDirs(absSrc, skip)
+	if err != nil {
+		t.Fatalf("resolve local modules of %s: %v", src, err)
+	}
+
+	root := absSrc
+	for _, dir := range external {
+		root = commonAncestor(root, dir)
+	}
+
+	dstRoot := t.TempDir()
+	for _, dir := range append([]string{absSrc}, external...) {
+		rel, err := filepath.Rel(root, dir)
+		if err != nil {
+			t.Fatalf("relate %s to %s: %v", dir, root, err)
+		}
+		if err := copyTree(dir, filepath.Join(dstRoot, rel), skip, t.Logf); err != nil {
+			t.Fatalf("copy stack to temp: %v", err)
+		}
+	}
+
+	rel, err := filepath.Rel(root, absSrc)
+	if err != nil {
+		t.Fatalf("relate %s to %s: %v", absSrc, root, err)
+	}
+	return filepath.Join(dstRoot, rel)
+}
+
+// hermeticGitHubBaseURL is a local port nothing listens on, so a provider
+// that tries to reach GitHub during a plan fails fast instead of touching the
+// network.
+const hermeticGitHubBaseURL = "http://127.0.0.1:1/"
+
+// hermeticOverrideFile is the override file copyHermeticStack writes. The
+// _override suffix makes tofu merge it into the stack's own provider block.
+const hermeticOverrideFile = "hermetic_override.tofu"
+
+// copyHermeticStack is copyStackToTemp for plan-only tests: the copy gains a
+// provider override that drops the token and points the GitHub provider at
+// hermeticGitHubBaseURL, so neither an ambient GITHUB_TOKEN nor the network
+// can change the plan. Any app_auth block is stripped from the copy as well,
+// since an override cannot remove it and the provider would otherwise fetch
+// an installation token at configure time. src must declare an unaliased
+// provider "github".
+func copyHermeticStack(t *testing.T, src string, skip ...string) string {
+	t.Helper()
+
+	dir := copyStackToTemp(t, src, skip...)
+	stripProviderAppAuth(t, dir)
+	writeProviderOverride(t, dir)
+	return dir
+}
+
+// stripProviderAppAuth removes app_auth blocks from every provider "github"
+// in the top-level configuration files of dir.
+func stripProviderAppAuth(t *testing.T, dir string) {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read %s: %v", dir, err)
+	}
+	for _, entry := range entries {
+		if ext := filepath.Ext(entry.Name()); entry.IsDir() || (ext != ".tf" && ext != ".tofu") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		file, diags := hclwrite.ParseConfig(data, path, hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Fatalf("parse %s: %s", path, diags.Error())
+		}
+		stripped := false
+		for _, block := range file.Body().Blocks() {
+			if block.Type() != "provider" || len(block.Labels()) == 0 || block.Labels()[0] != "github" {
+				continue
+			}
+			for _, nested := range block.Body().Blocks() {
+				if nested.Type() == "app_auth" {
+					stripped = block.Body().RemoveBlock(nested) || stripped
+				}
+			}
+		}
+		if !stripped {
+			continue
+		}
+		if err := os.WriteFile(path, file.Bytes(), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+}
+
+// writeProviderOverride writes hermeticOverrideFile into dir.
+func writeProviderOverride(t *testing.T, dir string) {
+	t.Helper()
+
+	override := fmt.Sprintf(`# Written by copyHermeticStack; keeps plans off the network.
+provider "github" {
+  token    = ""
+  base_url = %q
+}
+`, hermeticGitHubBaseURL)
+	path := filepath.Join(dir, hermeticOverrideFile)
+	if err := os.WriteFile(path, []byte(override), 0o644); err != nil {
+		t.Fatalf("write provider override %s: %v", path, err)
+	}
+}
+
+func copyTree(src, dst string, skip []string, logf func(format string, args ...interface{})) error {
+	ctx := copyContext{src: src, dst: dst, skip: skip, logf: logf}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		return copyStackEntry(ctx, path, d, err)
+	})
+}
+
+// externalModuleDirs follows local module sources ("./" or "../") from every
+// HCL file under src, transitively, and returns the module directories that
+// live outside src.
+func externalModuleDirs(src string, skip []string) ([]string, error) {
+	seen := map[string]bool{src: true}
+	queue := []string{src}
+	var external []string
+	for len(queue) > 0 {
+		dir := queue[0]
+		queue = queue[1:]
+
+		sources, err := localModuleSources(dir, skip)
+		if err != nil {
+			return nil, err
+		}
+		for _, source := range sources {
+			if seen[source] || isWithin(source, src) {
+				continue
+			}
+			seen[source] = true
+			external = append(external, source)
+			queue = append(queue, source)
+		}
+	}
+	sort.Strings(external)
+	return external, nil
+}
+
+// localModuleSources returns the absolute directories named by local module
+// sources in the .tf and .tofu files under dir.
+func localModuleSources(dir string, skip []string) ([]string, error) {
+	parser := hclparse.NewParser()
+	var sources []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && shouldSkipPath(rel, skip) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinked files are left to copySymlink; their targets inside the
+		// tree are scanned in their own right.
+		if !d.Type().IsRegular() || (filepath.Ext(path) != ".tf" && filepath.Ext(path) != ".tofu") {
+			return nil
+		}
+
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return fmt.Errorf("parse %s: %s", path, diags.Error())
+		}
+		body, ok := file.Body.(*hclsyntax.Body)
+		if !ok {
+			return fmt.Errorf("%s: unexpected body type %T", path, file.Body)
+		}
+		for _, blk := range body.Blocks {
+			if blk.Type != "module" {
+				continue
+			}
+			attr, ok := blk.Body.Attributes["source"]
+			if !ok {
+				continue
+			}
+			value, diags := attr.Expr.Value(nil)
+			if diags.HasErrors() || value.Type() != cty.String {
+				continue
+			}
+			source := value.AsString()
+			if strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
+				sources = append(sources, filepath.Join(filepath.Dir(path), filepath.FromSlash(source)))
+			}
+		}
+		return nil
+	})
+	return sources, err
+}
+
+// isWithin reports whether path is dir or lies beneath it.
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// commonAncestor returns the deepest directory containing both a and b.
+func commonAncestor(a, b string) string {
+	for !isWithin(b, a) {
+		parent := filepath.Dir(a)
+		if parent == a {
+			return a
+		}
+		a = parent
+	}
+	return a
+}
+
+func copyStackEntry(ctx copyContext, path string, d fs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+
+	rel, err := filepath.Rel(ctx.src, path)
+	if err != nil {
+		return err
+	}
+
+	if rel == "." {
+		return nil
+	}
+
+	if shouldSkipPath(rel, ctx.skip) {
+		if d.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+
+	target := filepath.Join(ctx.dst, rel)
+	if d.Type()&fs.ModeSymlink != 0 {
+		return copySymlink(ctx, path, target)
+	}
+	if d.IsDir() {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(target, info.Mode().Perm()); err != nil {
+			return err
+		}
+		// MkdirAll is subject to the umask, so apply the mode explicitly.
+		return os.Chmod(target, info.Mode().Perm())
+	}
+
+	return copyFile(path, target)
+}
+
+// copySymlink recreates a link that stays inside the source tree as a
+// relative link in the copy. Links escaping the tree are an error, since the
+// workspace would depend on files outside it; dangling links are skipped.
+func copySymlink(ctx copyContext, path, target string) error {
+	link, err := os.Readlink(path)
+	if err != nil {
+		return err
+	}
+
+	resolved := link
+	if !filepath.IsAbs(link) {
+		resolved = filepath.Join(filepath.Dir(path), link)
+	}
+	if !isWithin(resolved, ctx.src) {
+		return fmt.Errorf("symlink %s points outside %s: %s", path, ctx.src, link)
+	}
+	if _, err := os.Stat(resolved); err != nil {
+		if ctx.logf != nil {
+			ctx.logf("skipping dangling symlink %s -> %s", path, link)
+		}
+		return nil
+	}
+	if filepath.IsAbs(link) {
+		if link, err = filepath.Rel(filepath.Dir(path), resolved); err != nil {
+			return err
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	return os.Symlink(link, target)
+}
+
+// shouldSkipPath reports whether rel matches any of the .gitignore-style
+// patterns; a leading "/" anchors a pattern to the copy root.
+func shouldSkipPath(rel string, patterns []string) bool {
+	rel = filepath.ToSlash(rel)
+	base := path.Base(rel)
+	for _, pattern := range patterns {
+		subject := base
+		if strings.Contains(pattern, "/") {
+			pattern = strings.TrimPrefix(pattern, "/")
+			subject = rel
+		}
+		if matched, _ := path.Match(pattern, subject); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// copyFile copies src to dst, carrying over the source permission bits so
+// executable hooks and 0600 templates keep their modes.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return err
+	}
+	return out.Sync()
+}
+
+// writeTree creates files under root from a map of slash-separated relative
+// paths to contents.
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+
+	for rel, content := range files {
+		target := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			t.Fatalf("create %s: %v", filepath.Dir(target), err)
+		}
+		if err := os.WriteFile(target, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", target, err)
+		}
+	}
+}
+
+// TestCopyStackToTempSkipsMatchingPaths checks nested matches are skipped
+// while files whose names merely resemble a pattern are still copied.
+func TestCopyStackToTempSkipsMatchingPaths(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"main.tofu":                          "",
+		".terraform.lock.hcl":                "",
+		".terraform/providers/marker":        "",
+		"nested/crash.log":                   "",
+		"nested/crash.log.md":                "",
+		"nested/terraform.tfstate":           "",
+		"nested/terraform.tfstate.example":   "",
+		"nested/.terraform.lock.hcl":         "",
+		"nested/.terraform/providers/marker": "",
+	})
+
+	dst := copyStackToTemp(t, src)
+
+	for _, skipped := range []string{".terraform.lock.hcl", ".terraform", "nested/crash.log", "nested/terraform.tfstate", "nested/.terraform"} {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(skipped))); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be skipped, stat err %v", skipped, err)
+		}
+	}
+	for _, copied := range []string{"main.tofu", "nested/crash.log.md", "nested/terraform.tfstate.example", "nested/.terraform.lock.hcl"} {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(copied))); err != nil {
+			t.Errorf("expected %s to be copied: %v", copied, err)
+		}
+	}
+}
+
+// TestCopyStackToTempAcceptsCustomPatterns checks a caller-supplied list
+// replaces the defaults.
+func TestCopyStackToTempAcceptsCustomPatterns(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"main.tofu":            "",
+		"local.auto.tfvars":    "",
+		"nested/local.tfvars":  "",
+		"nested/crash.log":     "",
+		"nested/tfvars.readme": "",
+	})
+
+	dst := copyStackToTemp(t, src, "*.tfvars")
+
+	for _, skipped := range []string{"local.auto.tfvars", "nested/local.tfvars"} {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(skipped))); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be skipped, stat err %v", skipped, err)
+		}
+	}
+	for _, copied := range []string{"main.tofu", "nested/crash.log", "nested/tfvars.readme"} {
+		if _, err := os.Stat(filepath.Join(dst, filepath.FromSlash(copied))); err != nil {
+			t.Errorf("expected %s to be copied: %v", copied, err)
+		}
+	}
+}
+
+// TestCopyStackToTempPreservesModes checks file and directory permission bits
+// survive the copy.
+func TestCopyStackToTempPreservesModes(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{
+		"private/secrets.tfvars.tmpl": "",
+		"hooks/pre-init":              "#!/bin/sh\n",
+	})
+	modes := map[string]os.FileMode{
+		"private/secrets.tfvars.tmpl": 0o600,
+		"hooks/pre-init":              0o755,
+		"private":                     0o700,
+	}
+	for rel, mode := range modes {
+		if err := os.Chmod(filepath.Join(src, filepath.FromSlash(rel)), mode); err != nil {
+			t.Fatalf("chmod %s: %v", rel, err)
+		}
+	}
+
+	dst := copyStackToTemp(t, src)
+
+	for rel, want := range modes {
+		info, err := os.Stat(filepath.Join(dst, filepath.FromSlash(rel)))
+		if err != nil {
+			t.Fatalf("stat copied %s: %v", rel, err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("expected %s to keep mode %o, got %o", rel, want, got)
+		}
+	}
+}
+
+// TestCopyStackToTempFollowsSiblingModules copies a stack whose module lives
+// beside it and checks the relative source still resolves in the copy.
+func TestCopyStackToTempFollowsSiblingModules(t *testing.T) {
+	root := t.TempDir()
+	stackMain := "module \"shared\" {\n  source = \"../../modules/shared\"\n}\n"
+	writeTree(t, root, map[string]string{
+		"stacks/app/main.tofu":      stackMain,
+		"modules/shared/main.tofu":  "module \"leaf\" {\n  source = \"../leaf\"\n}\n",
+		"modules/leaf/main.tofu":    "",
+		"modules/unused/main.tofu":  "",
+		"stacks/other/unrelated.tf": "",
+	})
+
+	dst := copyStackToTemp(t, filepath.Join(root, "stacks", "app"))
+
+	copied, err := os.ReadFile(filepath.Join(dst, "main.tofu"))
+	if err != nil {
+		t.Fatalf("read copied stack: %v", err)
+	}
+	if string(copied) != stackMain {
+		t.Fatalf("expected module source to be left untouched, got %q", copied)
+	}
+	for _, module := range []string{"shared", "leaf"} {
+		if _, err := os.Stat(filepath.Join(dst, "..", "..", "modules", module, "main.tofu")); err != nil {
+			t.Errorf("expected module %s to be copied beside the stack: %v", module, err)
+		}
+	}
+	for _, absent := range []string{filepath.Join("modules", "unused"), filepath.Join("stacks", "other")} {
+		if _, err := os.Stat(filepath.Join(dst, "..", "..", absent)); !os.IsNotExist(err) {
+			t.Errorf("expected %s not to be copied, stat err %v", absent, err)
+		}
+	}
+}
+
+// TestCopyStackToTempHandlesSymlinks checks intra-tree links are recreated
+// as relative links and dangling links are skipped.
+func TestCopyStackToTempHandlesSymlinks(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, map[string]string{"shared/common.tofu": "# shared\n"})
+	links := map[string]string{
+		"stack/common.tofu": filepath.Join("..", "shared", "common.tofu"),
+		"stack/absolute.tf": filepath.Join(src, "shared", "common.tofu"),
+		"stack/dangling.tf": "missing.tf",
+	}
+	for rel, link := range links {
+		path := filepath.Join(src, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create %s: %v", filepath.Dir(path), err)
+		}
+		if err := os.Symlink(link, path); err != nil {
+			t.Fatalf("symlink %s: %v", rel, err)
+		}
+	}
+
+	dst := copyStackToTemp(t, src)
+
+	for _, rel := range []string{"stack/common.tofu", "stack/absolute.tf"} {
+		copied := filepath.Join(dst, filepath.FromSlash(rel))
+		link, err := os.Readlink(copied)
+		if err != nil {
+			t.Fatalf("expected %s to be copied as a symlink: %v", rel, err)
+		}
+		if filepath.IsAbs(link) {
+			t.Errorf("expected %s to be a relative link, got %s", rel, link)
+		}
+		if content, err := os.ReadFile(copied); err != nil || string(content) != "# shared\n" {
+			t.Errorf("expected %s to resolve inside the copy, got %q (%v)", rel, content, err)
+		}
+	}
+	if _, err := os.Lstat(filepath.Join(dst, "stack", "dangling.tf")); !os.IsNotExist(err) {
+		t.Errorf("expected dangling symlink to be skipped, lstat err %v", err)
+	}
+}
+
+// TestCopyTreeRejectsEscapingSymlinks checks a link out of the source tree
+// fails the copy instead of dragging outside files into the workspace.
+func TestCopyTreeRejectsEscapingSymlinks(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secrets.tfvars")
+	writeTree(t, filepath.Dir(outside), map[string]string{"secrets.tfvars": ""})
+	src := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(src, "secrets.tfvars")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	err := copyTree(src, t.TempDir(), defaultCopySkipPatterns, t.Logf)
+	if err == nil || !strings.Contains(err.Error(), "points outside") {
+		t.Fatalf("expected escaping symlink to be rejected, got %v", err)
+	}
+}
+
+// TestCopyHermeticStackOverridesProvider checks the copy carries an override
+// that blanks the token and aims the provider at the dead endpoint.
+func TestCopyHermeticStackOverridesProvider(t *testing.T) {
+	dir := copyHermeticStack(t, filepath.Join("..", "modules", "repository", "tests", "fixture"))
+
+	file, diags := hclparse.NewParser().ParseHCLFile(filepath.Join(dir, hermeticOverrideFile))
+	if diags.HasErrors() {
+		t.Fatalf("parse provider override: %s", diags.Error())
+	}
+	provider := findBlock(file.Body.(*hclsyntax.Body), "provider", "github")
+	if provider == nil {
+		t.Fatalf("expected the override to declare provider \"github\"")
+	}
+	if got := findAttributeString(t, provider, "base_url"); got != hermeticGitHubBaseURL {
+		t.Fatalf("expected base_url %q, got %q", hermeticGitHubBaseURL, got)
+	}
+	if got := findAttributeString(t, provider, "token"); got != "" {
+		t.Fatalf("expected the override to blank the token, got %q", got)
+	}
+}
+
+// TestCopyHermeticStackStripsAppAuth checks the root stack copy keeps its
+// github provider but loses the app_auth block the override cannot remove.
+func TestCopyHermeticStackStripsAppAuth(t *testing.T) {
+	dir := copyHermeticStack(t, "..")
+
+	file, diags := hclparse.NewParser().ParseHCLFile(filepath.Join(dir, "terraform.tofu"))
+	if diags.HasErrors() {
+		t.Fatalf("parse copied provider: %s", diags.Error())
+	}
+	provider := findBlock(file.Body.(*hclsyntax.Body), "provider", "github")
+	if provider == nil {
+		t.Fatalf("expected the copy to keep provider \"github\"")
+	}
+	if appAuth := findBlock(provider.Body, "app_auth"); appAuth != nil {
+		t.Fatalf("expected app_auth to be stripped from the hermetic copy")
+	}
+}
+
+// TestHermeticPlanNeedsNoToken plans the repository fixture with no token in
+// the environment or configuration and expects it to succeed offline.
+func TestHermeticPlanNeedsNoToken(t *testing.T) {
+	t.Parallel()
+
+	forEachTofu(t, func(t *testing.T, binary string) {
+		dir := copyHermeticStack(t, filepath.Join("..", "modules", "repository", "tests", "fixture"))
+		options := terraformOptions(t, binary, dir)
+		options.EnvVars["GITHUB_TOKEN"] = ""
+
+		planStruct := tracedPlan(t, options)
+		assertPlannedAction(t, planStruct, "module.repository.github_repository.this", "create")
+	})
+}
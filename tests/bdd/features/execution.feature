@@ -35,14 +35,14 @@ Feature: Running estate execution commands
     Then the command exits with code 0
     And the execution workspace remains on disk
 
-  Scenario: Plan requires GITHUB_TOKEN
+  Scenario: Plan requires GitHub App credentials
     Given a fake estate repository is registered
-    And GITHUB_TOKEN is unset
+    And GitHub App credentials are unset
     When I run concordat plan
-    Then the command fails with message "GITHUB_TOKEN"
+    Then the command fails with message "GITHUB_APP_ID"
 
   Scenario: Plan requires an active estate
-    Given GITHUB_TOKEN is set to "placeholder-token"
+    Given GitHub App credentials are set
     When I run concordat plan
     Then the command fails with message "No active estate configured"
 
@@ -17,6 +17,7 @@ from pytest_bdd import given, parsers, scenarios, then, when
 from concordat import cli
 from concordat.errors import ConcordatError
 from concordat.estate import EstateRecord, register_estate
+from concordat.estate_execution import GITHUB_APP_ENV
 from concordat.persistence.backend import (
     ALL_BACKEND_ENV_VARS,
     AWS_BACKEND_ENV,
@@ -24,6 +25,7 @@ from concordat.persistence.backend import (
     SCW_BACKEND_ENV,
     SPACES_BACKEND_ENV,
 )
+from tests.helpers.github_app import FAKE_GITHUB_APP
 from tests.helpers.persistence import seed_persistence_files
 
 from .conftest import RunResult
@@ -87,7 +89,7 @@ def given_fake_estate(
         config_path=config_path,
         set_active_if_missing=True,
     )
-    monkeypatch.setenv("GITHUB_TOKEN", "placeholder-token")
+    _export_github_app(monkeypatch)
     execution_state["estate_repo_path"] = repo_path
 
 
@@ -121,16 +123,23 @@ def given_estate_persistence(execution_state: dict[str, typ.Any]) -> None:
     )
 
 
-@given("GITHUB_TOKEN is unset")
-def given_token_unset(monkeypatch: pytest.MonkeyPatch) -> None:
-    """Clear any configured GitHub token."""
-    monkeypatch.delenv("GITHUB_TOKEN", raising=False)
+@given("GitHub App credentials are unset")
+def given_github_app_unset(monkeypatch: pytest.MonkeyPatch) -> None:
+    """Clear the GitHub App credentials, leaving only a personal token."""
+    for name in GITHUB_APP_ENV:
+        monkeypatch.delenv(name, raising=False)
+    monkeypatch.setenv("GITHUB_TOKEN", "placeholder-token")
+
+
+@given("GitHub App credentials are set")
+def given_github_app_set(monkeypatch: pytest.MonkeyPatch) -> None:
+    """Export placeholder GitHub App credentials for the provider."""
+    _export_github_app(monkeypatch)
 
 
-@given(parsers.cfparse('GITHUB_TOKEN is set to "{token}"'))
-def given_token_set(token: str, monkeypatch: pytest.MonkeyPatch) -> None:
-    """Force concordat to use the provided GitHub token."""
-    monkeypatch.setenv("GITHUB_TOKEN", token)
+def _export_github_app(monkeypatch: pytest.MonkeyPatch) -> None:
+    for name, value in FAKE_GITHUB_APP.as_environment().items():
+        monkeypatch.setenv(name, value)
 
 
 def _set_backend_credentials(
@@ -432,6 +441,7 @@ def then_no_backend_secrets(cli_invocation: dict[str, RunResult]) -> None:
             "SPACES_ACCESS_KEY_ID",
             "SPACES_SECRET_ACCESS_KEY",
             "GITHUB_TOKEN",
+            "GITHUB_APP_PEM_FILE",
         )
         if (value := os.environ.get(env_var)) and value.strip()
     )
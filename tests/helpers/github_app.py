@@ -0,0 +1,11 @@
+"""Shared GitHub App credentials for execution tests."""
+
+from __future__ import annotations
+
+from concordat.estate_execution import GitHubAppCredentials
+
+FAKE_GITHUB_APP = GitHubAppCredentials(
+    app_id="123456",
+    installation_id="7890123",
+    pem_file="placeholder-pem",
+)
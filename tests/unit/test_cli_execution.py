@@ -11,6 +11,8 @@ import pytest
 from concordat import cli
 from concordat.errors import ConcordatError
 from concordat.estate import EstateRecord
+from concordat.estate_execution import GITHUB_APP_ENV
+from tests.helpers.github_app import FAKE_GITHUB_APP
 
 
 @dataclasses.dataclass(frozen=True)
@@ -33,6 +35,11 @@ def _estate_record() -> EstateRecord:
     )
 
 
+def _set_github_app_env(monkeypatch: pytest.MonkeyPatch) -> None:
+    for name, value in FAKE_GITHUB_APP.as_environment().items():
+        monkeypatch.setenv(name, value)
+
+
 def _apply_and_capture(
     monkeypatch: pytest.MonkeyPatch,
     *args: str,
@@ -41,7 +48,7 @@ def _apply_and_capture(
     """Run cli.apply with a fake executor and capture forwarded kwargs."""
     record = _estate_record()
     monkeypatch.setattr(cli, "get_active_estate", lambda: record)
-    monkeypatch.setenv("GITHUB_TOKEN", "token")
+    _set_github_app_env(monkeypatch)
 
     captured: dict[str, object] = {}
 
@@ -80,11 +87,13 @@ def test_plan_requires_active_estate(monkeypatch: pytest.MonkeyPatch) -> None:
         cli.plan()
 
 
-def test_plan_runs_with_injected_token(monkeypatch: pytest.MonkeyPatch) -> None:
-    """Plan resolves the token and forwards arguments to run_plan."""
+def test_plan_runs_with_github_app_credentials(
+    monkeypatch: pytest.MonkeyPatch,
+) -> None:
+    """Plan resolves the app credentials and forwards arguments to run_plan."""
     record = _estate_record()
     monkeypatch.setattr(cli, "get_active_estate", lambda: record)
-    monkeypatch.setenv("GITHUB_TOKEN", "token")
+    _set_github_app_env(monkeypatch)
 
     called: dict[str, object] = {}
 
@@ -109,10 +118,11 @@ def test_plan_runs_with_injected_token(monkeypatch: pytest.MonkeyPatch) -> None:
     assert options.keep_workdir is True
 
 
-def test_plan_allows_explicit_token(monkeypatch: pytest.MonkeyPatch) -> None:
-    """--github-token overrides the environment variable."""
+def test_plan_reads_github_app_credentials(monkeypatch: pytest.MonkeyPatch) -> None:
+    """Plan forwards the GitHub App credentials exported in the environment."""
     record = _estate_record()
     monkeypatch.setattr(cli, "get_active_estate", lambda: record)
+    _set_github_app_env(monkeypatch)
 
     captured: dict[str, object] = {}
 
@@ -121,23 +131,37 @@ def test_plan_allows_explicit_token(monkeypatch: pytest.MonkeyPatch) -> None:
         options: ExecutionOptions,
         io: ExecutionIO,
     ) -> tuple[int, Path]:
-        captured["record"] = record
         captured["options"] = options
-        captured["io"] = io
         return 0, Path("dummy-workdir")
 
     monkeypatch.setattr(cli, "run_plan", fake_run_plan)
-    auth_value = "placeholder-value"
-    cli.plan(github_token=auth_value)
+    cli.plan()
 
-    assert typ.cast("ExecutionOptions", captured["options"]).github_token == auth_value
+    options = typ.cast("ExecutionOptions", captured["options"])
+    assert options.github_app == FAKE_GITHUB_APP
+
+
+@pytest.mark.parametrize("missing", GITHUB_APP_ENV)
+def test_plan_requires_github_app_credentials(
+    monkeypatch: pytest.MonkeyPatch,
+    missing: str,
+) -> None:
+    """Plan names each GitHub App variable that is not exported."""
+    record = _estate_record()
+    monkeypatch.setattr(cli, "get_active_estate", lambda: record)
+    _set_github_app_env(monkeypatch)
+    monkeypatch.delenv(missing)
+    monkeypatch.setenv("GITHUB_TOKEN", "token")
+
+    with pytest.raises(ConcordatError, match=missing):
+        cli.plan()
 
 
 def test_apply_requires_auto_approve(monkeypatch: pytest.MonkeyPatch) -> None:
     """Apply refuses to run without --auto-approve."""
     record = _estate_record()
     monkeypatch.setattr(cli, "get_active_estate", lambda: record)
-    monkeypatch.setenv("GITHUB_TOKEN", "token")
+    _set_github_app_env(monkeypatch)
 
     with pytest.raises(ConcordatError):
         cli.apply()
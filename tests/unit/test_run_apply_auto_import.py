@@ -7,6 +7,7 @@ import typing as typ
 from types import SimpleNamespace
 
 from concordat.estate_execution import ExecutionIO, ExecutionOptions, run_apply
+from tests.helpers.github_app import FAKE_GITHUB_APP
 from tests.unit.conftest import _make_record
 
 if typ.TYPE_CHECKING:  # pragma: no cover
@@ -66,7 +67,7 @@ def test_run_apply_offers_to_import_existing_github_repositories(
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
     options = ExecutionOptions(
         github_owner="leynos",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         extra_args=("-auto-approve",),
     )
 
@@ -142,7 +143,7 @@ def test_run_apply_imports_existing_repo_with_fallback_id(
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
     options = ExecutionOptions(
         github_owner="leynos",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         extra_args=("-auto-approve",),
     )
 
@@ -210,7 +211,7 @@ def test_run_apply_non_interactive_does_not_attempt_auto_import(
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=stderr_buffer)
     options = ExecutionOptions(
         github_owner="leynos",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         extra_args=("-auto-approve",),
     )
 
@@ -270,7 +271,7 @@ def test_run_apply_user_declines_auto_import_prompt(
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
     options = ExecutionOptions(
         github_owner="leynos",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         extra_args=("-auto-approve",),
     )
 
@@ -338,7 +339,7 @@ def test_run_apply_all_import_attempts_fail(
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=stderr_buffer)
     options = ExecutionOptions(
         github_owner="leynos",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         extra_args=("-auto-approve",),
     )
 
@@ -13,6 +13,7 @@ if typ.TYPE_CHECKING:  # pragma: no cover
     from tests.conftest import GitRepo
 
 from concordat.estate_execution import ExecutionIO, ExecutionOptions, run_apply
+from tests.helpers.github_app import FAKE_GITHUB_APP
 from tests.unit.conftest import _make_record
 
 
@@ -182,7 +183,7 @@ def _setup_test_environment(
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
     options = ExecutionOptions(
         github_owner="leynos",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         extra_args=("-auto-approve",),
     )
 
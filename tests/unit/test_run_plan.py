@@ -21,6 +21,7 @@ from concordat.persistence.backend import (
     ALL_BACKEND_ENV_VARS,
     AWS_SESSION_TOKEN_VAR,
 )
+from tests.helpers.github_app import FAKE_GITHUB_APP
 from tests.helpers.persistence import (
     PersistenceTestConfig,
     seed_invalid_persistence_manifest,
@@ -78,7 +79,7 @@ def _run_plan_test(
 
     options = ExecutionOptions(
         github_owner="example",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         environment=options_environment,
     )
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
@@ -107,7 +108,7 @@ def test_run_plan_uses_persistence_backend_when_enabled(
     stderr_buffer = io.StringIO()
     options = ExecutionOptions(
         github_owner="example",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
     )
     io_streams = ExecutionIO(stdout=stdout_buffer, stderr=stderr_buffer)
 
@@ -153,7 +154,7 @@ def test_run_plan_executes_from_tofu_directory_when_present(
 
     options = ExecutionOptions(
         github_owner="example",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
     )
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
 
@@ -202,7 +203,7 @@ def test_run_plan_sanitizes_inventory_yaml_directives_for_tofu(
 
     options = ExecutionOptions(
         github_owner="example",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
         keep_workdir=True,
     )
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
@@ -268,7 +269,7 @@ def test_run_plan_surfaces_the_cli_plan_diff_output(
     io_streams = ExecutionIO(stdout=stdout_buffer, stderr=io.StringIO())
     options = ExecutionOptions(
         github_owner="example",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
     )
 
     exit_code, _ = run_plan(_make_record(git_repo.path), options, io_streams)
@@ -330,7 +331,7 @@ def test_run_plan_backend_config_validation(
     monkeypatch.setattr("concordat.estate_execution.Tofu", _fail_init)
     options = ExecutionOptions(
         github_owner="example",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
     )
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
 
@@ -425,7 +426,7 @@ def test_run_plan_requires_backend_credentials(
     monkeypatch.setattr("concordat.estate_execution.Tofu", _fail_init)
     options = ExecutionOptions(
         github_owner="example",
-        github_token="token",  # noqa: S106
+        github_app=FAKE_GITHUB_APP,
     )
     io_streams = ExecutionIO(stdout=io.StringIO(), stderr=io.StringIO())
 
@@ -567,3 +568,20 @@ def test_run_plan_rejects_invalid_persistence_manifest(
 
     with pytest.raises(EstateExecutionError):
         _run_plan_test(git_repo, monkeypatch, fake_tofu)
+
+
+def test_run_plan_hands_tofu_github_app_credentials(
+    monkeypatch: pytest.MonkeyPatch,
+    git_repo: GitRepo,
+    fake_tofu: list[typ.Any],
+) -> None:
+    """Tofu receives the GitHub App credentials and never a GITHUB_TOKEN."""
+    monkeypatch.setenv("GITHUB_TOKEN", "ambient-token")
+
+    exit_code, _, tofu = _run_plan_test(git_repo, monkeypatch, fake_tofu)
+
+    assert exit_code == 0
+    assert tofu.env["GITHUB_APP_ID"] == FAKE_GITHUB_APP.app_id
+    assert tofu.env["GITHUB_APP_INSTALLATION_ID"] == FAKE_GITHUB_APP.installation_id
+    assert tofu.env["GITHUB_APP_PEM_FILE"] == FAKE_GITHUB_APP.pem_file
+    assert "GITHUB_TOKEN" not in tofu.env